@@ -0,0 +1,325 @@
+package goffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotAvailable matches the CapabilityError of a component missing from the ffmpeg build
+var ErrNotAvailable = errors.New("not available")
+
+// CapabilityError reports a component a job needs but ffmpeg lacks
+type CapabilityError struct {
+	Kind string // encoder, decoder, muxer, demuxer, filter, protocol, pixel format or hwaccel
+	Name string
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("%s %s not available", e.Kind, e.Name)
+}
+
+// Is matches ErrNotAvailable, and ErrUnknownEncoder for missing encoders
+func (e *CapabilityError) Is(target error) bool {
+	return target == ErrNotAvailable || (target == ErrUnknownEncoder && e.Kind == "encoder")
+}
+
+// MediaType is the kind of stream a codec or filter pad handles
+type MediaType string
+
+const (
+	MediaTypeVideo      MediaType = "video"
+	MediaTypeAudio      MediaType = "audio"
+	MediaTypeSubtitle   MediaType = "subtitle"
+	MediaTypeData       MediaType = "data"
+	MediaTypeAttachment MediaType = "attachment"
+)
+
+var mediaTypes = map[byte]MediaType{
+	'V': MediaTypeVideo, 'A': MediaTypeAudio, 'S': MediaTypeSubtitle, 'D': MediaTypeData, 'T': MediaTypeAttachment,
+}
+
+// Codec is an encoder or a decoder listed by ffmpeg -encoders or -decoders
+type Codec struct {
+	Name         string
+	Type         MediaType
+	Description  string
+	Flags        string // raw flags column, e.g. "V....D"
+	Experimental bool
+}
+
+// Format is a muxer or a demuxer listed by ffmpeg -muxers or -demuxers
+type Format struct {
+	Name        string // first name of the format, e.g. "mov" for "mov,mp4,m4a,3gp,3g2,mj2"
+	Aliases     []string
+	Description string
+	Device      bool
+}
+
+// Filter is a filter listed by ffmpeg -filters
+type Filter struct {
+	Name        string
+	Inputs      string // pad types, e.g. "V" or "N" for a dynamic number of pads
+	Outputs     string
+	Description string
+	Timeline    bool
+}
+
+// PixelFormat is a pixel format listed by ffmpeg -pix_fmts
+type PixelFormat struct {
+	Name         string
+	Input        bool
+	Output       bool
+	HWAccel      bool
+	Components   int
+	BitsPerPixel int
+}
+
+// Capabilities are the components of an ffmpeg build, keyed by name
+type Capabilities struct {
+	Encoders        map[string]Codec
+	Decoders        map[string]Codec
+	Muxers          map[string]Format
+	Demuxers        map[string]Format // keyed by every alias
+	Filters         map[string]Filter
+	InputProtocols  map[string]bool
+	OutputProtocols map[string]bool
+	PixelFormats    map[string]PixelFormat
+	HWAccels        map[string]bool
+}
+
+func (c *Capabilities) HasEncoder(name string) bool {
+	_, ok := c.Encoders[name]
+	return ok
+}
+
+func (c *Capabilities) HasDecoder(name string) bool {
+	_, ok := c.Decoders[name]
+	return ok
+}
+
+func (c *Capabilities) HasMuxer(name string) bool {
+	_, ok := c.Muxers[name]
+	return ok
+}
+
+func (c *Capabilities) HasDemuxer(name string) bool {
+	_, ok := c.Demuxers[name]
+	return ok
+}
+
+func (c *Capabilities) HasFilter(name string) bool {
+	_, ok := c.Filters[name]
+	return ok
+}
+
+func (c *Capabilities) HasPixelFormat(name string) bool {
+	_, ok := c.PixelFormats[name]
+	return ok
+}
+
+func (c *Capabilities) HasHWAccel(name string) bool {
+	return c.HWAccels[name]
+}
+
+// QueryCapabilities runs ffmpeg with each listing option and parses the results
+func QueryCapabilities(ctx context.Context, cfg Configuration) (*Capabilities, error) {
+	list := func(option string) ([]byte, error) {
+		var stdout, stderr bytes.Buffer
+		proc := cfg.Executor().Command(ctx, cfg.FFmpegBinPath(), "-hide_banner", option)
+		proc.Stdout, proc.Stderr = &stdout, &stderr
+		if err := proc.Run(); err != nil {
+			return nil, NewFFmpegError(proc.Args, err, strings.Split(strings.TrimSpace(stderr.String()), "\n"))
+		}
+		return stdout.Bytes(), nil
+	}
+
+	c := new(Capabilities)
+	for _, query := range []struct {
+		option string
+		parse  func(*Capabilities, []byte)
+	}{
+		{"-encoders", func(c *Capabilities, out []byte) { c.Encoders = ParseCodecs(out) }},
+		{"-decoders", func(c *Capabilities, out []byte) { c.Decoders = ParseCodecs(out) }},
+		{"-muxers", func(c *Capabilities, out []byte) { c.Muxers = ParseFormats(out) }},
+		{"-demuxers", func(c *Capabilities, out []byte) { c.Demuxers = ParseFormats(out) }},
+		{"-filters", func(c *Capabilities, out []byte) { c.Filters = ParseFilters(out) }},
+		{"-protocols", func(c *Capabilities, out []byte) { c.InputProtocols, c.OutputProtocols = ParseProtocols(out) }},
+		{"-pix_fmts", func(c *Capabilities, out []byte) { c.PixelFormats = ParsePixelFormats(out) }},
+		{"-hwaccels", func(c *Capabilities, out []byte) { c.HWAccels = ParseHWAccels(out) }},
+	} {
+		out, err := list(query.option)
+		if err != nil {
+			return nil, err
+		}
+		query.parse(c, out)
+	}
+	return c, nil
+}
+
+// listedLines returns the lines after the "---" separator ending the legend
+func listedLines(out []byte) []string {
+	var lines []string
+	listed := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !listed {
+			listed = strings.HasPrefix(line, "--")
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ParseCodecs parses the output of ffmpeg -encoders or -decoders
+func ParseCodecs(out []byte) map[string]Codec {
+	codecs := make(map[string]Codec)
+	for _, line := range listedLines(out) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		flags := fields[0]
+		codecs[fields[1]] = Codec{
+			Name:         fields[1],
+			Type:         mediaTypes[flags[0]],
+			Description:  description(line, 2),
+			Flags:        flags,
+			Experimental: len(flags) > 3 && flags[3] == 'X',
+		}
+	}
+	return codecs
+}
+
+// ParseFormats parses the output of ffmpeg -muxers or -demuxers, keying the
+// formats by each of their comma-separated names
+func ParseFormats(out []byte) map[string]Format {
+	formats := make(map[string]Format)
+	// Unset flags are printed as spaces, so the flags column is as wide as the separator
+	width := -1
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " ")
+		if width < 0 {
+			if separator := strings.TrimSpace(line); strings.HasPrefix(separator, "--") {
+				width = len(separator)
+			}
+			continue
+		}
+		if len(line) <= width+1 {
+			continue
+		}
+		fields := strings.Fields(line[width+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		names := strings.Split(fields[0], ",")
+		format := Format{
+			Name:        names[0],
+			Aliases:     names[1:],
+			Description: description(line[width+1:], 1),
+			Device:      strings.Contains(line[1:width+1], "d"),
+		}
+		for _, name := range names {
+			formats[name] = format
+		}
+	}
+	return formats
+}
+
+// ParseFilters parses the output of ffmpeg -filters
+func ParseFilters(out []byte) map[string]Filter {
+	filters := make(map[string]Filter)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		// Legend lines are "T.. = Timeline support", filters "T.. name A->A description"
+		if len(fields) < 3 || fields[1] == "=" || !strings.Contains(fields[2], "->") {
+			continue
+		}
+		inputs, outputs, _ := strings.Cut(fields[2], "->")
+		filters[fields[1]] = Filter{
+			Name:        fields[1],
+			Inputs:      inputs,
+			Outputs:     outputs,
+			Description: description(line, 3),
+			Timeline:    fields[0][0] == 'T',
+		}
+	}
+	return filters
+}
+
+// ParseProtocols parses the output of ffmpeg -protocols
+func ParseProtocols(out []byte) (input, output map[string]bool) {
+	input, output = make(map[string]bool), make(map[string]bool)
+	var current map[string]bool
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "Input:":
+			current = input
+		case line == "Output:":
+			current = output
+		case line != "" && current != nil && !strings.HasSuffix(line, ":"):
+			current[line] = true
+		}
+	}
+	return input, output
+}
+
+// ParsePixelFormats parses the output of ffmpeg -pix_fmts
+func ParsePixelFormats(out []byte) map[string]PixelFormat {
+	formats := make(map[string]PixelFormat)
+	for _, line := range listedLines(out) {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || len(fields[0]) < 3 {
+			continue
+		}
+		flags := fields[0]
+		format := PixelFormat{
+			Name:    fields[1],
+			Input:   flags[0] == 'I',
+			Output:  flags[1] == 'O',
+			HWAccel: flags[2] == 'H',
+		}
+		fmt.Sscan(fields[2], &format.Components)
+		fmt.Sscan(fields[3], &format.BitsPerPixel)
+		formats[format.Name] = format
+	}
+	return formats
+}
+
+// ParseHWAccels parses the output of ffmpeg -hwaccels
+func ParseHWAccels(out []byte) map[string]bool {
+	hwaccels := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasSuffix(line, ":") {
+			hwaccels[line] = true
+		}
+	}
+	return hwaccels
+}
+
+// description returns the text of line after its first n fields
+func description(line string, n int) string {
+	for i := 0; i < n; i++ {
+		line = strings.TrimSpace(line)
+		if end := strings.IndexAny(line, " \t"); end >= 0 {
+			line = line[end:]
+		} else {
+			return ""
+		}
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,130 @@
+package goffmpeg
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/graux/goffmpeg/pkg/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+var capabilityOutputs = map[string]string{
+	"-encoders": `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ..S... = Slice-level multithreading
+ ...X.. = Codec is experimental
+ ....B. = Supports draw_horiz_band
+ .....D = Supports direct rendering method 1
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)
+ A..X.D opus                 Opus
+ S..... mov_text             3GPP Timed Text subtitle
+`,
+	"-decoders": `Decoders:
+ ------
+ VFS..D h264                 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10
+`,
+	"-muxers": `File formats:
+ D.. = Demuxing supported
+ .E. = Muxing supported
+ ..d = Is a device
+ ---
+  E  hls             Apple HTTP Live Streaming
+  E  mp4             MP4 (MPEG-4 Part 14)
+`,
+	"-demuxers": `File formats:
+ D.. = Demuxing supported
+ .E. = Muxing supported
+ ..d = Is a device
+ ---
+ D   mov,mp4,m4a,3gp,3g2,mj2 QuickTime / MOV
+ D d v4l2            Video4Linux2 device grab
+`,
+	"-filters": `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  V = Video input/output
+  N = Dynamic number and/or type of input/output
+  | = Source or sink filter
+ ..C amix              N->A       Audio mixing.
+ TSC scale             V->V       Scale the input video size and/or convert the image format.
+ ... nullsrc           |->V       Null video source, return unprocessed video frames.
+`,
+	"-protocols": `Supported file protocols:
+Input:
+  file
+  http
+Output:
+  file
+  rtmp
+`,
+	"-pix_fmts": `Pixel formats:
+I.... = Supported Input  format for conversion
+.O... = Supported Output format for conversion
+..H.. = Hardware accelerated format
+-----
+IO... yuv420p                3             12      8-8-8
+..H.. vaapi                  0              0      0
+`,
+	"-hwaccels": `Hardware acceleration methods:
+vaapi
+cuda
+
+`,
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Run("Should query and parse every listing", func(t *testing.T) {
+		executor := cmd.ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "printf", "%s", capabilityOutputs[args[len(args)-1]])
+		})
+
+		cfg, err := Configure(context.Background(), WithExecutor(executor), WithFFmpegPath("ffmpeg"), WithFFprobePath("ffprobe"), WithCapabilities())
+		assert.Nil(t, err)
+		c := cfg.Capabilities()
+		assert.NotNil(t, c)
+
+		assert.Equal(t, Codec{Name: "libx264", Type: MediaTypeVideo, Description: "libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)", Flags: "V....D"}, c.Encoders["libx264"])
+		assert.True(t, c.Encoders["opus"].Experimental)
+		assert.Equal(t, MediaTypeSubtitle, c.Encoders["mov_text"].Type)
+		assert.True(t, c.HasDecoder("h264"))
+		assert.True(t, c.HasMuxer("hls"))
+		assert.False(t, c.HasMuxer("dash"))
+		assert.True(t, c.HasDemuxer("m4a"))
+		assert.Equal(t, "mov", c.Demuxers["mp4"].Name)
+		assert.True(t, c.Demuxers["v4l2"].Device)
+		assert.False(t, c.Demuxers["mov"].Device)
+		assert.Equal(t, Filter{Name: "scale", Inputs: "V", Outputs: "V", Description: "Scale the input video size and/or convert the image format.", Timeline: true}, c.Filters["scale"])
+		assert.Equal(t, "|", c.Filters["nullsrc"].Inputs)
+		assert.Len(t, c.Filters, 3)
+		assert.Equal(t, map[string]bool{"file": true, "http": true}, c.InputProtocols)
+		assert.Equal(t, map[string]bool{"file": true, "rtmp": true}, c.OutputProtocols)
+		assert.Equal(t, PixelFormat{Name: "yuv420p", Input: true, Output: true, Components: 3, BitsPerPixel: 12}, c.PixelFormats["yuv420p"])
+		assert.True(t, c.PixelFormats["vaapi"].HWAccel)
+		assert.Equal(t, map[string]bool{"vaapi": true, "cuda": true}, c.HWAccels)
+	})
+
+	t.Run("Should match missing components", func(t *testing.T) {
+		err := error(&CapabilityError{Kind: "encoder", Name: "libx265"})
+		assert.EqualError(t, err, "encoder libx265 not available")
+		assert.True(t, errors.Is(err, ErrNotAvailable))
+		assert.True(t, errors.Is(err, ErrUnknownEncoder))
+		assert.False(t, errors.Is(&CapabilityError{Kind: "filter", Name: "zscale"}, ErrUnknownEncoder))
+	})
+}
+
+func TestParseFormats(t *testing.T) {
+	t.Run("Should parse the two flag columns of older versions", func(t *testing.T) {
+		formats := ParseFormats([]byte("File formats:\n D. = Demuxing supported\n .E = Muxing supported\n --\n DE matroska,webm   Matroska / WebM\n  E mp4             MP4 (MPEG-4 Part 14)\n"))
+		assert.Equal(t, Format{Name: "matroska", Aliases: []string{"webm"}, Description: "Matroska / WebM"}, formats["webm"])
+		assert.Equal(t, "MP4 (MPEG-4 Part 14)", formats["mp4"].Description)
+	})
+}
@@ -3,8 +3,8 @@ package goffmpeg
 import (
 	"context"
 	"errors"
-	"runtime"
-	"strings"
+	"fmt"
+	"os"
 
 	"github.com/graux/goffmpeg/pkg/cmd"
 )
@@ -12,11 +12,76 @@ import (
 const (
 	ffmpegCommand  = "ffmpeg"
 	ffprobeCommand = "ffprobe"
+
+	// EnvFFmpeg and EnvFFprobe override the lookup of the binaries, unless their
+	// path is given with WithFFmpegPath or WithFFprobePath
+	EnvFFmpeg  = "GOFFMPEG_FFMPEG"
+	EnvFFprobe = "GOFFMPEG_FFPROBE"
 )
 
+// ErrBinaryNotFound is returned by Configure when ffmpeg or ffprobe cannot be found
+var ErrBinaryNotFound = errors.New("binary not found")
+
 type Configuration struct {
 	ffprobeBinPath string
 	ffmpegBinPath  string
+	searchDirs     []string
+	workingDir     string
+	env            []string
+	executor       cmd.Executor
+	capabilities   *Capabilities
+	queryCaps      bool
+	ffmpegVersion  Version
+	ffprobeVersion Version
+	queryVersions  bool
+	minVersion     Version
+}
+
+// Option customizes the Configuration built by Configure
+type Option func(*Configuration)
+
+// WithExecutor runs ffmpeg and ffprobe through executor. As it may run them
+// on another host, e.g. in a container, binaries not given by path or
+// environment variable are then left to it by command name, without a lookup.
+func WithExecutor(executor cmd.Executor) Option {
+	return func(cfg *Configuration) {
+		cfg.executor = executor
+	}
+}
+
+// WithFFmpegPath uses path as the ffmpeg binary, without looking it up
+func WithFFmpegPath(path string) Option {
+	return func(cfg *Configuration) {
+		cfg.ffmpegBinPath = path
+	}
+}
+
+// WithFFprobePath uses path as the ffprobe binary, without looking it up
+func WithFFprobePath(path string) Option {
+	return func(cfg *Configuration) {
+		cfg.ffprobeBinPath = path
+	}
+}
+
+// WithSearchDirs looks the binaries up in dirs, in order, before the PATH
+func WithSearchDirs(dirs ...string) Option {
+	return func(cfg *Configuration) {
+		cfg.searchDirs = append(cfg.searchDirs, dirs...)
+	}
+}
+
+// WithWorkingDir runs ffmpeg and ffprobe in dir, so relative paths are resolved from it
+func WithWorkingDir(dir string) Option {
+	return func(cfg *Configuration) {
+		cfg.workingDir = dir
+	}
+}
+
+// WithEnv adds env, as "KEY=value" entries, to the environment of ffmpeg and ffprobe
+func WithEnv(env ...string) Option {
+	return func(cfg *Configuration) {
+		cfg.env = append(cfg.env, env...)
+	}
 }
 
 func (cfg Configuration) FFmpegBinPath() string {
@@ -27,41 +92,124 @@ func (cfg Configuration) FFprobeBinPath() string {
 	return cfg.ffprobeBinPath
 }
 
-func Configure(ctx context.Context) (Configuration, error) {
-	ffmpegBin, err := cmd.FindBinPath(ctx, ffmpegCommand)
-	if err != nil {
-		return Configuration{}, err
+// WithCapabilities queries the encoders, decoders, formats, filters, protocols,
+// pixel formats and hwaccels of ffmpeg while configuring
+func WithCapabilities() Option {
+	return func(cfg *Configuration) {
+		cfg.queryCaps = true
 	}
+}
 
-	if ffmpegBin == "" {
-		return Configuration{}, errors.New("ffmpeg not found, please install it before using goffmpeg")
+// WithVersions parses ffmpeg -version and ffprobe -version while configuring
+func WithVersions() Option {
+	return func(cfg *Configuration) {
+		cfg.queryVersions = true
 	}
+}
 
-	ffprobeBin, err := cmd.FindBinPath(ctx, ffprobeCommand)
-	if err != nil {
+// WithMinimumVersion fails Configure with ErrUnsupportedVersion when ffmpeg or
+// ffprobe are older than v, e.g. Version{Major: 5, Minor: 1}. It implies WithVersions.
+func WithMinimumVersion(v Version) Option {
+	return func(cfg *Configuration) {
+		cfg.queryVersions = true
+		cfg.minVersion = v
+	}
+}
+
+// FFmpegVersion Get the ffmpeg version, zero unless configured WithVersions
+func (cfg Configuration) FFmpegVersion() Version {
+	return cfg.ffmpegVersion
+}
+
+// FFprobeVersion Get the ffprobe version, zero unless configured WithVersions
+func (cfg Configuration) FFprobeVersion() Version {
+	return cfg.ffprobeVersion
+}
+
+// Capabilities Get the components of ffmpeg, nil unless configured WithCapabilities
+func (cfg Configuration) Capabilities() *Capabilities {
+	return cfg.capabilities
+}
+
+// WorkingDir Get the directory ffmpeg and ffprobe run in, empty for the current one
+func (cfg Configuration) WorkingDir() string {
+	return cfg.workingDir
+}
+
+// Env Get the entries added to the environment of ffmpeg and ffprobe
+func (cfg Configuration) Env() []string {
+	return cfg.env
+}
+
+// Executor Get the executor creating the ffmpeg and ffprobe processes, in the
+// working directory and with the environment of the configuration
+func (cfg Configuration) Executor() cmd.Executor {
+	executor := cfg.executor
+	if executor == nil {
+		executor = cmd.DefaultExecutor
+	}
+	if len(cfg.env) > 0 {
+		executor = cmd.WithEnv(executor, cfg.env...)
+	}
+	if cfg.workingDir != "" {
+		executor = cmd.WithDir(executor, cfg.workingDir)
+	}
+	return executor
+}
+
+// Configure resolves ffmpeg and ffprobe and applies opts. Each binary is the
+// path given by its option, else the one of its environment variable, else
+// the first one found in the search directories or the PATH, unless a custom
+// executor is set.
+func Configure(ctx context.Context, opts ...Option) (Configuration, error) {
+	var cfg Configuration
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var err error
+	if cfg.ffmpegBinPath, err = cfg.resolveBinPath(ffmpegCommand, cfg.ffmpegBinPath, EnvFFmpeg); err != nil {
+		return Configuration{}, err
+	}
+	if cfg.ffprobeBinPath, err = cfg.resolveBinPath(ffprobeCommand, cfg.ffprobeBinPath, EnvFFprobe); err != nil {
 		return Configuration{}, err
 	}
 
-	if ffprobeBin == "" {
-		return Configuration{}, errors.New("ffprobe not found, please install it before using goffmpeg")
+	if cfg.queryVersions {
+		if cfg.ffmpegVersion, cfg.ffprobeVersion, err = QueryVersions(ctx, cfg); err != nil {
+			return Configuration{}, err
+		}
+		if cfg.ffmpegVersion.Compare(cfg.minVersion) < 0 {
+			return Configuration{}, fmt.Errorf("%w: ffmpeg %s is older than %s", ErrUnsupportedVersion, cfg.ffmpegVersion, cfg.minVersion)
+		}
+		if cfg.ffprobeVersion.Compare(cfg.minVersion) < 0 {
+			return Configuration{}, fmt.Errorf("%w: ffprobe %s is older than %s", ErrUnsupportedVersion, cfg.ffprobeVersion, cfg.minVersion)
+		}
 	}
 
-	return Configuration{
-		ffmpegBinPath:  normalizeBinPath(ffmpegBin),
-		ffprobeBinPath: normalizeBinPath(ffprobeBin),
-	}, nil
+	if cfg.queryCaps {
+		if cfg.capabilities, err = QueryCapabilities(ctx, cfg); err != nil {
+			return Configuration{}, err
+		}
+	}
+	return cfg, nil
 }
 
-func normalizeBinPath(binPath string) string {
-	binPath = strings.ReplaceAll(binPath, lineSeparator(), " ")
-	return strings.TrimSpace(binPath)
-}
+func (cfg Configuration) resolveBinPath(command, path, envKey string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if path = os.Getenv(envKey); path != "" {
+		return path, nil
+	}
+	// The host PATH says nothing about where a custom executor runs commands
+	if cfg.executor != nil {
+		return command, nil
+	}
 
-func lineSeparator() string {
-	switch runtime.GOOS {
-	case "windows":
-		return "\r\n"
-	default:
-		return "\n"
+	path, err := cmd.LookPath(command, cfg.searchDirs...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s, install it or set %s: %w", ErrBinaryNotFound, command, envKey, err)
 	}
+	return path, nil
 }
@@ -2,8 +2,13 @@ package goffmpeg
 
 import (
 	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	"github.com/graux/goffmpeg/pkg/cmd"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,3 +21,83 @@ func TestConfigure(t *testing.T) {
 		assert.NotEmpty(t, cfg.FFprobeBinPath())
 	})
 }
+
+func TestConfigureWithOptions(t *testing.T) {
+	binaries := func(t *testing.T) string {
+		dir := t.TempDir()
+		for _, name := range []string{"ffmpeg", "ffprobe"} {
+			assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755))
+		}
+		return dir
+	}
+
+	t.Run("Should use the given paths and run them through the executor", func(t *testing.T) {
+		var names []string
+		executor := cmd.ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			names = append(names, name)
+			return exec.CommandContext(ctx, name, args...)
+		})
+
+		cfg, err := Configure(context.Background(),
+			WithExecutor(executor),
+			WithFFmpegPath("/opt/ffmpeg/bin/ffmpeg"),
+			WithFFprobePath("/opt/ffmpeg/bin/ffprobe"),
+			WithWorkingDir("/tmp/work"),
+			WithEnv("AV_LOG_FORCE_NOCOLOR=1"),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "/opt/ffmpeg/bin/ffmpeg", cfg.FFmpegBinPath())
+		assert.Equal(t, "/opt/ffmpeg/bin/ffprobe", cfg.FFprobeBinPath())
+		assert.Equal(t, "/tmp/work", cfg.WorkingDir())
+
+		c := cfg.Executor().Command(context.Background(), cfg.FFmpegBinPath(), "-version")
+		assert.Equal(t, []string{"/opt/ffmpeg/bin/ffmpeg"}, names)
+		assert.Equal(t, "/tmp/work", c.Dir)
+		assert.Equal(t, "AV_LOG_FORCE_NOCOLOR=1", c.Env[len(c.Env)-1])
+	})
+
+	t.Run("Should leave the binaries to a custom executor without looking them up", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		var commands [][]string
+		executor := cmd.ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			commands = append(commands, append([]string{name}, args...))
+			return exec.CommandContext(ctx, name, args...)
+		})
+
+		cfg, err := Configure(context.Background(), WithExecutor(cmd.WithPrefix(executor, "docker", "exec", "transcoder")))
+		assert.Nil(t, err)
+		assert.Equal(t, "ffmpeg", cfg.FFmpegBinPath())
+		assert.Equal(t, "ffprobe", cfg.FFprobeBinPath())
+
+		cfg.Executor().Command(context.Background(), cfg.FFprobeBinPath(), "-version")
+		assert.Equal(t, [][]string{{"docker", "exec", "transcoder", "ffprobe", "-version"}}, commands)
+	})
+
+	t.Run("Should prefer the environment variables to the lookup", func(t *testing.T) {
+		t.Setenv(EnvFFmpeg, "/usr/local/bin/ffmpeg")
+		t.Setenv(EnvFFprobe, "/usr/local/bin/ffprobe")
+
+		cfg, err := Configure(context.Background(), WithFFprobePath("/opt/ffprobe"), WithSearchDirs(binaries(t)))
+		assert.Nil(t, err)
+		assert.Equal(t, "/usr/local/bin/ffmpeg", cfg.FFmpegBinPath())
+		assert.Equal(t, "/opt/ffprobe", cfg.FFprobeBinPath())
+	})
+
+	t.Run("Should look the binaries up in the search directories first", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		dir := binaries(t)
+
+		cfg, err := Configure(context.Background(), WithSearchDirs(t.TempDir(), dir))
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(dir, "ffmpeg"), cfg.FFmpegBinPath())
+		assert.Equal(t, filepath.Join(dir, "ffprobe"), cfg.FFprobeBinPath())
+	})
+
+	t.Run("Should name the missing binary", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+
+		_, err := Configure(context.Background(), WithFFmpegPath("/opt/ffmpeg"))
+		assert.True(t, errors.Is(err, ErrBinaryNotFound))
+		assert.ErrorContains(t, err, "binary not found: ffprobe, install it or set GOFFMPEG_FFPROBE")
+	})
+}
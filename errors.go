@@ -0,0 +1,127 @@
+package goffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// DefaultStderrLines is the number of trailing stderr lines kept in an FFmpegError
+const DefaultStderrLines = 20
+
+// Error classes of an FFmpegError, to be matched with errors.Is
+var (
+	ErrInputNotFound    = errors.New("input not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnknownEncoder   = errors.New("unknown encoder")
+	ErrInvalidOption    = errors.New("invalid option")
+	ErrUnsupportedCodec = errors.New("unsupported codec")
+	ErrDiskFull         = errors.New("disk full")
+	ErrKilled           = errors.New("killed")
+)
+
+// stderrPatterns are the classes of an error and the stderr messages giving them.
+// Classes earlier in the list win when a line matches several.
+var stderrPatterns = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrDiskFull, []string{"No space left on device", "Disk quota exceeded"}},
+	{ErrPermissionDenied, []string{"Permission denied", "Operation not permitted", "Server returned 401", "Server returned 403"}},
+	{ErrInputNotFound, []string{"No such file or directory", "Server returned 404"}},
+	{ErrUnknownEncoder, []string{"Unknown encoder", "Encoder not found", "Unknown decoder"}},
+	{ErrUnsupportedCodec, []string{
+		"codec not currently supported in container", "Could not find tag for codec",
+		"Decoder not found", "Unsupported codec",
+	}},
+	{ErrInvalidOption, []string{
+		"Unrecognized option", "Option not found", "Error splitting the argument list",
+		"Trailing option(s) found", "Missing argument for option", "Failed to set value",
+	}},
+}
+
+// FFmpegError is returned when ffmpeg or ffprobe fail to start or exit unsuccessfully
+type FFmpegError struct {
+	Command  []string
+	ExitCode int
+	Signal   string
+	Stderr   []string
+	Kind     error
+	Err      error
+}
+
+// NewFFmpegError builds an FFmpegError from the error returned by running command
+// and the last lines written to stderr
+func NewFFmpegError(command []string, err error, stderr []string) *FFmpegError {
+	ffErr := &FFmpegError{
+		Command:  command,
+		ExitCode: -1,
+		Stderr:   stderr,
+		Err:      err,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		ffErr.ExitCode = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			ffErr.Signal = status.Signal().String()
+			ffErr.Kind = ErrKilled
+			return ffErr
+		}
+	}
+
+	ffErr.Kind = classifyStderr(stderr)
+	return ffErr
+}
+
+// classifyStderr returns the class of the last line matching a pattern, as
+// ffmpeg prints the fatal error after any warnings
+func classifyStderr(lines []string) error {
+	for i := len(lines) - 1; i >= 0; i-- {
+		for _, class := range stderrPatterns {
+			for _, pattern := range class.patterns {
+				if strings.Contains(lines[i], pattern) {
+					return class.kind
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *FFmpegError) Error() string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "%s failed", e.binary())
+	if e.Signal != "" {
+		fmt.Fprintf(&msg, " with signal %s", e.Signal)
+	} else if e.ExitCode >= 0 {
+		fmt.Fprintf(&msg, " with exit code %d", e.ExitCode)
+	} else if e.Err != nil {
+		fmt.Fprintf(&msg, ": %s", e.Err)
+	}
+	if e.Kind != nil {
+		fmt.Fprintf(&msg, " (%s)", e.Kind)
+	}
+	if len(e.Stderr) > 0 {
+		fmt.Fprintf(&msg, ": %s", e.Stderr[len(e.Stderr)-1])
+	}
+	return msg.String()
+}
+
+func (e *FFmpegError) binary() string {
+	if len(e.Command) == 0 {
+		return "ffmpeg"
+	}
+	return e.Command[0]
+}
+
+// Is reports whether target is the class of the error
+func (e *FFmpegError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *FFmpegError) Unwrap() error {
+	return e.Err
+}
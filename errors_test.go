@@ -0,0 +1,51 @@
+package goffmpeg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFFmpegError(t *testing.T) {
+	t.Run("Should classify the stderr lines", func(t *testing.T) {
+		cases := map[string]error{
+			"missing.mp4: No such file or directory":                      ErrInputNotFound,
+			"out.mp4: Permission denied":                                  ErrPermissionDenied,
+			"Unknown encoder 'libx265'":                                   ErrUnknownEncoder,
+			"Unrecognized option 'foo'.":                                  ErrInvalidOption,
+			"Failed to set value 'x' for option 'crf': Invalid argument":  ErrInvalidOption,
+			"Error opening output file out.mp4: Invalid argument":         nil,
+			"Subtitle codec 94213 is not supported.":                      nil,
+			"Could not find tag for codec pcm_s16le in stream #0":         ErrUnsupportedCodec,
+			"av_interleaved_write_frame(): No space left on device":       ErrDiskFull,
+			"Conversion failed!":                                          nil,
+			"[https @ 0x1] HTTP error 404 Not Found, Server returned 404": ErrInputNotFound,
+		}
+		for line, kind := range cases {
+			err := NewFFmpegError([]string{"ffmpeg", "-i", "in"}, errors.New("exit status 1"), []string{"ffmpeg version 6.0", line})
+			assert.Equal(t, kind, err.Kind, line)
+			if kind != nil {
+				assert.ErrorIs(t, err, kind, line)
+			}
+		}
+	})
+
+	t.Run("Should be decided by the last matching line", func(t *testing.T) {
+		err := NewFFmpegError([]string{"ffmpeg"}, errors.New("exit status 1"), []string{
+			"[hls @ 0x1] failed to delete old segment seg_001.ts: No such file or directory",
+			"[aac @ 0x2] Unknown encoder 'libfdk_aac'",
+			"Conversion failed!",
+		})
+		assert.Equal(t, ErrUnknownEncoder, err.Kind)
+	})
+
+	t.Run("Should keep the command and the underlying error", func(t *testing.T) {
+		cause := errors.New("exec: \"ffmpeg\": executable file not found in $PATH")
+		err := NewFFmpegError([]string{"ffmpeg", "-i", "in"}, cause, nil)
+		assert.ErrorIs(t, err, cause)
+		assert.Equal(t, -1, err.ExitCode)
+		assert.Equal(t, []string{"ffmpeg", "-i", "in"}, err.Command)
+		assert.Contains(t, err.Error(), "executable file not found")
+	})
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/graux/goffmpeg/media"
 	"github.com/graux/goffmpeg/transcoder"
 )
 
 const (
-	inputPath   = "../fixtures/input.3gp"
-	outputPath  = "../test_results/hls-output.mp4"
-	keyinfoPath = "keyinfo"
+	inputPath  = "../fixtures/input.3gp"
+	outputPath = "../test_results/hls-output.m3u8"
 )
 
 func main() {
@@ -22,13 +24,23 @@ func main() {
 
 	trans.MediaFile().SetVideoCodec("libx264")
 	trans.MediaFile().SetHlsSegmentDuration(4)
-	trans.MediaFile().SetEncryptionKey(keyinfoPath)
+	trans.MediaFile().SetHLSEncryption(&media.HLSEncryption{
+		KeyURI:           "https://keys.example.com/hls-output-%d.key",
+		RotationInterval: 10 * time.Second,
+		OnKey: func(key *media.HLSKey) error {
+			fmt.Printf("key %d: %x iv %s\n", key.Index, key.Key, key.IVHex())
+			return nil
+		},
+	})
+
+	job, err := trans.Start(context.Background(), true)
+	if err != nil {
+		panic(err)
+	}
 
-	done := trans.Run(true)
-	progress := trans.Output()
-	for p := range progress {
+	for p := range job.Progress() {
 		fmt.Println(p)
 	}
 
-	fmt.Println(<-done)
+	fmt.Println(job.Wait())
 }
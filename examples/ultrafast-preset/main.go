@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/graux/goffmpeg/transcoder"
@@ -21,11 +22,14 @@ func main() {
 
 	trans.MediaFile().SetPreset("ultrafast")
 
-	done := trans.Run(true)
-	progress := trans.Output()
-	for p := range progress {
+	job, err := trans.Start(context.Background(), true)
+	if err != nil {
+		panic(err)
+	}
+
+	for p := range job.Progress() {
 		fmt.Println(p)
 	}
 
-	fmt.Println(<-done)
+	fmt.Println(job.Wait())
 }
@@ -0,0 +1,234 @@
+// Package goffmpegtest provides scriptable fake ffmpeg and ffprobe binaries for
+// tests of code built on goffmpeg.
+//
+// The fakes are the test binary itself, run again in fake mode, so the package
+// using them must call Main from its TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		goffmpegtest.Main(m)
+//	}
+package goffmpegtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/pkg/cmd"
+)
+
+const (
+	envMode   = "GOFFMPEGTEST_MODE"
+	envScript = "GOFFMPEGTEST_SCRIPT"
+	envCalls  = "GOFFMPEGTEST_CALLS"
+
+	modeFake = "fake"
+)
+
+// Script is what a fake binary does once started, in order: write Stdout and
+// the Stderr lines, write the Progress blocks to the -progress target, then
+// wait for Delay, or forever when Hang is set, and exit with ExitCode. Sending
+// "q" on stdin ends the script early with exit code 0 unless IgnoreQuit is set.
+type Script struct {
+	Stdout           string
+	Stderr           []string
+	Progress         []ProgressBlock
+	ProgressInterval time.Duration
+	Delay            time.Duration
+	Hang             bool
+	IgnoreQuit       bool
+	IgnoreTerm       bool // ignore SIGTERM and interrupts, so only SIGKILL stops the fake
+	ExitCode         int
+}
+
+// ProgressBlock is one block of key=value lines written by -progress. The
+// "progress" key is written last, and defaults to "continue", or "end" for the
+// last block of a script.
+type ProgressBlock map[string]string
+
+// NewProgressBlock builds a block reporting frame and outTime at speed
+func NewProgressBlock(frame int, outTime time.Duration, speed float64) ProgressBlock {
+	return ProgressBlock{
+		"frame":       strconv.Itoa(frame),
+		"out_time":    formatOutTime(outTime),
+		"out_time_us": strconv.FormatInt(outTime.Microseconds(), 10),
+		"out_time_ms": strconv.FormatInt(outTime.Microseconds(), 10),
+		"speed":       strconv.FormatFloat(speed, 'f', -1, 64) + "x",
+	}
+}
+
+func formatOutTime(d time.Duration) string {
+	us := d.Microseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%06d", us/3600e6, us/60e6%60, us/1e6%60, us%1e6)
+}
+
+func (b ProgressBlock) lines(last bool) []string {
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		if key != "progress" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		lines = append(lines, key+"="+b[key])
+	}
+	progress := b["progress"]
+	if progress == "" {
+		progress = "continue"
+		if last {
+			progress = "end"
+		}
+	}
+	return append(lines, "progress="+progress)
+}
+
+// Call is one run of a fake binary
+type Call struct {
+	Name string   // "ffmpeg" or "ffprobe"
+	Args []string // arguments after the binary name
+}
+
+// Fake runs the FFmpeg and FFprobe scripts in place of the real binaries.
+// Scripts are read when a command is created, so they can be changed between runs.
+type Fake struct {
+	FFmpeg  Script
+	FFprobe Script
+
+	t   testing.TB
+	dir string
+	mu  sync.Mutex
+	n   int
+}
+
+// New returns a fake whose files are removed at the end of the test
+func New(t testing.TB) *Fake {
+	t.Helper()
+	return &Fake{t: t, dir: t.TempDir()}
+}
+
+// Executor Get the executor running the fakes instead of ffmpeg and ffprobe,
+// recognized by the base name of the command
+func (f *Fake) Executor() cmd.Executor {
+	return cmd.ExecutorFunc(f.command)
+}
+
+// Configuration Get a configuration running the fakes
+func (f *Fake) Configuration() goffmpeg.Configuration {
+	f.t.Helper()
+	cfg, err := goffmpeg.Configure(context.Background(),
+		goffmpeg.WithExecutor(f.Executor()),
+		goffmpeg.WithFFmpegPath("ffmpeg"),
+		goffmpeg.WithFFprobePath("ffprobe"),
+	)
+	if err != nil {
+		f.t.Fatalf("goffmpegtest: configure: %s", err)
+	}
+	return cfg
+}
+
+// Calls Get the runs of the fakes so far, in the order they started
+func (f *Fake) Calls() []Call {
+	f.t.Helper()
+	file, err := os.Open(f.callsPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		f.t.Fatalf("goffmpegtest: %s", err)
+	}
+	defer file.Close()
+
+	var calls []Call
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var call Call
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			f.t.Fatalf("goffmpegtest: invalid call record: %s", err)
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// LastCall Get the last run of the named fake, or nil
+func (f *Fake) LastCall(name string) *Call {
+	calls := f.Calls()
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Name == name {
+			return &calls[i]
+		}
+	}
+	return nil
+}
+
+func (f *Fake) callsPath() string {
+	return filepath.Join(f.dir, "calls.jsonl")
+}
+
+func (f *Fake) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	f.t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		f.t.Fatalf("goffmpegtest: %s", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(name), ".exe")
+	script := f.FFmpeg
+	if base == "ffprobe" {
+		script = f.FFprobe
+	}
+	scriptPath := f.writeScript(base, script)
+
+	c := exec.CommandContext(ctx, self, args...)
+	c.Args[0] = base
+	c.Env = childEnv(envMode+"="+modeFake, envScript+"="+scriptPath, envCalls+"="+f.callsPath())
+	return c
+}
+
+// childEnv Get the environment of a fake process. Race enabled binaries sleep
+// for a second on exit by default, longer than the grace periods tests use.
+func childEnv(env ...string) []string {
+	env = append(env, "GORACE="+strings.TrimSpace(os.Getenv("GORACE")+" atexit_sleep_ms=0"))
+	return append(os.Environ(), env...)
+}
+
+func (f *Fake) writeScript(name string, script Script) string {
+	f.mu.Lock()
+	f.n++
+	path := filepath.Join(f.dir, name+"-"+strconv.Itoa(f.n)+".json")
+	f.mu.Unlock()
+
+	content, err := json.Marshal(script)
+	if err == nil {
+		err = os.WriteFile(path, content, 0600)
+	}
+	if err != nil {
+		f.t.Fatalf("goffmpegtest: %s", err)
+	}
+	return path
+}
+
+// Main runs the fake binary when the test binary was started as one, and the
+// tests otherwise
+func Main(m *testing.M) {
+	switch os.Getenv(envMode) {
+	case modeFake:
+		os.Exit(fake(os.Args))
+	}
+	os.Exit(m.Run())
+}
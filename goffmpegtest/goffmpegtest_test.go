@@ -0,0 +1,68 @@
+package goffmpegtest
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	Main(m)
+}
+
+func TestFake(t *testing.T) {
+	t.Run("Should resolve the binaries to the fakes", func(t *testing.T) {
+		cfg := New(t).Configuration()
+		require.Equal(t, "ffmpeg", cfg.FFmpegBinPath())
+		require.Equal(t, "ffprobe", cfg.FFprobeBinPath())
+	})
+
+	t.Run("Should answer with the canned output and record the arguments", func(t *testing.T) {
+		fake := New(t)
+		fake.FFprobe.Stdout = `{"format":{"duration":"10.0"}}`
+		cfg := fake.Configuration()
+
+		out, err := cfg.Executor().Command(context.Background(), cfg.FFprobeBinPath(), "-show_format", "in.mp4").Output()
+		require.NoError(t, err)
+		require.Equal(t, `{"format":{"duration":"10.0"}}`, string(out))
+		require.Equal(t, &Call{Name: "ffprobe", Args: []string{"-show_format", "in.mp4"}}, fake.LastCall("ffprobe"))
+		require.Nil(t, fake.LastCall("ffmpeg"))
+	})
+
+	t.Run("Should exit with the scripted code and stderr", func(t *testing.T) {
+		fake := New(t)
+		fake.FFmpeg = Script{Stderr: []string{"in.mp4: No such file or directory"}, ExitCode: 1}
+		cfg := fake.Configuration()
+
+		_, err := cfg.Executor().Command(context.Background(), cfg.FFmpegBinPath(), "-i", "in.mp4").Output()
+		var exitErr *exec.ExitError
+		require.True(t, errors.As(err, &exitErr))
+		require.Equal(t, 1, exitErr.ExitCode())
+		require.Equal(t, "in.mp4: No such file or directory\n", string(exitErr.Stderr))
+	})
+
+	t.Run("Should hang until the context kills it", func(t *testing.T) {
+		fake := New(t)
+		fake.FFmpeg.Hang = true
+		cfg := fake.Configuration()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		err := cfg.Executor().Command(ctx, cfg.FFmpegBinPath()).Run()
+		require.Error(t, err)
+		require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+}
+
+func TestProgressBlock(t *testing.T) {
+	t.Run("Should write the progress key last", func(t *testing.T) {
+		block := NewProgressBlock(25, 1500*time.Millisecond, 2)
+		require.Equal(t, []string{
+			"frame=25", "out_time=00:00:01.500000", "out_time_ms=1500000", "out_time_us=1500000", "speed=2x", "progress=end",
+		}, block.lines(true))
+	})
+}
@@ -0,0 +1,137 @@
+package goffmpegtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// fake runs the script of a fake binary, started with argv
+func fake(argv []string) int {
+	var script Script
+	content, err := os.ReadFile(os.Getenv(envScript))
+	if err == nil {
+		err = json.Unmarshal(content, &script)
+	}
+	if err == nil {
+		err = record(Call{Name: argv[0], Args: argv[1:]})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goffmpegtest: %s\n", err)
+		return 1
+	}
+
+	if script.IgnoreTerm {
+		signal.Ignore(os.Interrupt, syscall.SIGTERM)
+	}
+	quit := make(chan struct{})
+	if !script.IgnoreQuit {
+		go watchQuit(os.Stdin, quit)
+	}
+
+	io.WriteString(os.Stdout, script.Stdout)
+	for _, line := range script.Stderr {
+		fmt.Fprintln(os.Stderr, line)
+	}
+
+	if len(script.Progress) > 0 {
+		progress, err := progressWriter(argv[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goffmpegtest: %s\n", err)
+			return 1
+		}
+		for i, block := range script.Progress {
+			if i > 0 && wait(quit, script.ProgressInterval) {
+				return 0
+			}
+			last := i == len(script.Progress)-1
+			fmt.Fprintln(progress, strings.Join(block.lines(last), "\n"))
+		}
+		progress.Close()
+	}
+
+	if script.Hang {
+		<-quit
+		return 0
+	}
+	if wait(quit, script.Delay) {
+		return 0
+	}
+	return script.ExitCode
+}
+
+// wait sleeps for d and reports whether "q" was received meanwhile
+func wait(quit <-chan struct{}, d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-quit:
+			return true
+		default:
+			return false
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-quit:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func watchQuit(r io.Reader, quit chan<- struct{}) {
+	reader := bufio.NewReader(r)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		if b == 'q' {
+			close(quit)
+			return
+		}
+	}
+}
+
+// progressWriter opens the target of the -progress option
+func progressWriter(args []string) (io.WriteCloser, error) {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] != "-progress" {
+			continue
+		}
+		target := args[i+1]
+		if fd, ok := strings.CutPrefix(target, "pipe:"); ok {
+			n, err := strconv.Atoi(fd)
+			if err != nil {
+				return nil, fmt.Errorf("invalid progress target %q", target)
+			}
+			return os.NewFile(uintptr(n), target), nil
+		}
+		return os.Create(target)
+	}
+	return nil, fmt.Errorf("progress blocks scripted without -progress option")
+}
+
+func record(call Call) error {
+	line, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(os.Getenv(envCalls), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"errors"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/pkg/filtergraph"
+)
+
+// CheckCapabilities reports every encoder, format, filter, pixel format and
+// hwaccel the file and its outputs need but c lacks, as joined CapabilityErrors
+func (m *File) CheckCapabilities(c *goffmpeg.Capabilities) error {
+	check := capabilityCheck{capabilities: c, seen: make(map[goffmpeg.CapabilityError]bool)}
+
+	if m.hwaccel != "" && m.hwaccel != "auto" && m.hwaccel != "none" && !c.HasHWAccel(m.hwaccel) {
+		check.missing("hwaccel", m.hwaccel)
+	}
+	for _, input := range m.inputs {
+		if input.Format != "" && !c.HasDemuxer(input.Format) {
+			check.missing("demuxer", input.Format)
+		}
+	}
+	for _, file := range append([]*File{m}, m.outputs...) {
+		file.checkOutput(&check)
+	}
+	return errors.Join(check.errs...)
+}
+
+func (m *File) checkOutput(check *capabilityCheck) {
+	c := check.capabilities
+	for _, encoder := range []string{m.videoCodec, m.audioCodec, m.subtitleCodec} {
+		check.encoder(encoder)
+	}
+	if m.outputFormat != "" && !c.HasMuxer(m.outputFormat) {
+		check.missing("muxer", m.outputFormat)
+	}
+	if m.pixFmt != "" && !c.HasPixelFormat(m.pixFmt) {
+		check.missing("pixel format", m.pixFmt)
+	}
+
+	var filters []string
+	for _, graph := range []string{m.videoFilter, m.audioFilter} {
+		filters = append(filters, filtergraph.FilterNames(graph)...)
+	}
+	if m.filterGraph != nil {
+		filters = append(filters, filtergraph.FilterNames(m.filterGraph.String())...)
+	}
+	if m.burnSubtitles != nil {
+		filters = append(filters, "subtitles")
+	}
+
+	var renditions []Rendition
+	if m.hlsLadder != nil {
+		check.muxer("hls")
+		for _, rendition := range m.hlsLadder.Renditions {
+			renditions = append(renditions, rendition.Rendition)
+		}
+		for _, audio := range m.hlsLadder.Audio {
+			check.encoder(defaultCodec(audio.Codec, "aac"))
+		}
+	}
+	if m.dashLadder != nil {
+		check.muxer("dash")
+		renditions = append(renditions, m.dashLadder.Renditions...)
+		for _, audio := range m.dashLadder.Audio {
+			check.encoder(defaultCodec(audio.Codec, "aac"))
+		}
+	}
+	for _, rendition := range renditions {
+		r := rendition.resolve(nil)
+		check.encoder(r.VideoCodec)
+		if r.AudioBitRate > 0 {
+			check.encoder(r.AudioCodec)
+		}
+		filters = append(filters, "scale")
+	}
+
+	for _, filter := range filters {
+		if !c.HasFilter(filter) {
+			check.missing("filter", filter)
+		}
+	}
+}
+
+type capabilityCheck struct {
+	capabilities *goffmpeg.Capabilities
+	seen         map[goffmpeg.CapabilityError]bool
+	errs         []error
+}
+
+func (check *capabilityCheck) encoder(name string) {
+	if name != "" && name != "copy" && !check.capabilities.HasEncoder(name) {
+		check.missing("encoder", name)
+	}
+}
+
+func (check *capabilityCheck) muxer(name string) {
+	if !check.capabilities.HasMuxer(name) {
+		check.missing("muxer", name)
+	}
+}
+
+// missing records a missing component once
+func (check *capabilityCheck) missing(kind, name string) {
+	err := goffmpeg.CapabilityError{Kind: kind, Name: name}
+	if !check.seen[err] {
+		check.seen[err] = true
+		check.errs = append(check.errs, &err)
+	}
+}
+
+func defaultCodec(codec, fallback string) string {
+	if codec == "" {
+		return fallback
+	}
+	return codec
+}
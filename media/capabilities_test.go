@@ -0,0 +1,42 @@
+package media
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/graux/goffmpeg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	capabilities := &goffmpeg.Capabilities{
+		Encoders: map[string]goffmpeg.Codec{"libx264": {}, "aac": {}},
+		Muxers:   map[string]goffmpeg.Format{"mp4": {}, "hls": {}},
+		Filters:  map[string]goffmpeg.Filter{"scale": {}},
+	}
+
+	t.Run("Should accept files using available components", func(t *testing.T) {
+		file := &File{}
+		file.SetVideoCodec("libx264")
+		file.SetAudioCodec("copy")
+		file.SetVideoFilter("scale=1280:-2")
+		file.SetHLSLadder(&HLSLadder{Renditions: []HLSRendition{{Rendition: Rendition{Height: 720, AudioBitRate: 128000}}}})
+		require.NoError(t, file.CheckCapabilities(capabilities))
+	})
+
+	t.Run("Should report every missing component once", func(t *testing.T) {
+		file := &File{}
+		file.SetVideoCodec("libx265")
+		file.SetVideoFilter("zscale=t=linear,scale=1280:-2,zscale=t=bt709")
+		file.SetOutputFormat("mp4")
+
+		output := &File{}
+		output.SetVideoCodec("libx265")
+		output.SetOutputFormat("webm")
+		file.AddOutput(output)
+
+		err := file.CheckCapabilities(capabilities)
+		require.EqualError(t, err, "encoder libx265 not available\nfilter zscale not available\nmuxer webm not available")
+		require.True(t, errors.Is(err, goffmpeg.ErrUnknownEncoder))
+	})
+}
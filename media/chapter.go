@@ -0,0 +1,47 @@
+package media
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Chapter is a chapter of the input, read WithChapters
+type Chapter struct {
+	ID           int64             `json:"id"`
+	TimeBase     string            `json:"time_base"`
+	Start        int64             `json:"start"`
+	StartTimeStr string            `json:"start_time"`
+	StartTime    time.Duration     `json:"-"`
+	End          int64             `json:"end"`
+	EndTimeStr   string            `json:"end_time"`
+	EndTime      time.Duration     `json:"-"`
+	Tags         map[string]string `json:"tags"`
+}
+
+func (c *Chapter) UnmarshalJSON(bytes []byte) error {
+	type Alias Chapter
+	chapter := new(Alias)
+	if err := json.Unmarshal(bytes, chapter); err != nil {
+		return err
+	}
+	*c = Chapter(*chapter)
+	c.StartTime = parseSeconds(c.StartTimeStr)
+	c.EndTime = parseSeconds(c.EndTimeStr)
+	return nil
+}
+
+// Title Get the title tag of the chapter
+func (c Chapter) Title() string {
+	return c.Tags["title"]
+}
+
+// Program is a program of a transport stream, read WithPrograms
+type Program struct {
+	ProgramID  int               `json:"program_id"`
+	ProgramNum int               `json:"program_num"`
+	NbStreams  int               `json:"nb_streams"`
+	PmtPid     int               `json:"pmt_pid"`
+	PcrPid     int               `json:"pcr_pid"`
+	Tags       map[string]string `json:"tags"`
+	Streams    []Stream          `json:"streams"`
+}
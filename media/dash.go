@@ -0,0 +1,135 @@
+package media
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DASHAudioRendition is an audio representation of a DASH ladder
+type DASHAudioRendition struct {
+	Language string          `json:"language,omitempty"`
+	Source   StreamSpecifier `json:"source,omitempty"`   // the nth audio stream of input 0 when unset, n being the rendition index
+	Codec    string          `json:"codec,omitempty"`    // aac when empty
+	BitRate  int             `json:"bit_rate,omitempty"` // bits per second
+}
+
+// DASHLadder is an MPEG-DASH output with fMP4 segments. Video renditions share
+// one adaptation set and audio renditions get one adaptation set per language.
+type DASHLadder struct {
+	Renditions      []Rendition          `json:"renditions,omitempty"`
+	Audio           []DASHAudioRendition `json:"audio,omitempty"`
+	SegmentDuration int                  `json:"segment_duration,omitempty"` // seconds
+	UseTemplate     bool                 `json:"use_template,omitempty"`
+	UseTimeline     bool                 `json:"use_timeline,omitempty"`
+	HLSPlaylist     bool                 `json:"hls_playlist,omitempty"`   // also write HLS playlists for the same segments
+	InitSegName     string               `json:"init_seg_name,omitempty"`  // ffmpeg default when empty
+	MediaSegName    string               `json:"media_seg_name,omitempty"` // ffmpeg default when empty
+}
+
+// Args returns the output options of the ladder, before the MPD path
+func (l *DASHLadder) Args(metadata *Metadata) []string {
+	var args []string
+
+	for i, rendition := range l.Renditions {
+		r := rendition.resolve(metadata)
+		index := strconv.Itoa(i)
+		args = append(args,
+			"-map", MapTypeIndex(0, StreamTypeVideo, 0).String(),
+			"-filter:v:"+index, r.scaleFilter(),
+			"-c:v:"+index, r.VideoCodec,
+		)
+		if r.VideoProfile != "" {
+			args = append(args, "-profile:v:"+index, r.VideoProfile)
+		}
+		args = append(args, r.levelArgs(index)...)
+		if r.VideoBitRate > 0 {
+			args = append(args, "-b:v:"+index, strconv.Itoa(r.VideoBitRate))
+		}
+		if r.MaxRate > 0 {
+			args = append(args, "-maxrate:v:"+index, strconv.Itoa(r.MaxRate))
+		}
+		if r.BufSize > 0 {
+			args = append(args, "-bufsize:v:"+index, strconv.Itoa(r.BufSize))
+		}
+	}
+
+	for i, audio := range l.Audio {
+		index := strconv.Itoa(i)
+		codec := audio.Codec
+		if codec == "" {
+			codec = "aac"
+		}
+		args = append(args, "-map", audio.Source.orDefault(StreamTypeAudio, i).String(), "-c:a:"+index, codec)
+		if audio.BitRate > 0 {
+			args = append(args, "-b:a:"+index, strconv.Itoa(audio.BitRate))
+		}
+		if audio.Language != "" {
+			args = append(args, "-metadata:s:a:"+index, "language="+audio.Language)
+		}
+	}
+
+	args = append(args, "-f", "dash", "-dash_segment_type", "mp4", "-adaptation_sets", l.adaptationSets())
+	if l.SegmentDuration > 0 {
+		args = append(args, "-seg_duration", strconv.Itoa(l.SegmentDuration))
+	}
+	args = append(args,
+		"-use_template", boolArg(l.UseTemplate),
+		"-use_timeline", boolArg(l.UseTimeline),
+	)
+	if l.HLSPlaylist {
+		args = append(args, "-hls_playlist", "1")
+	}
+	if l.InitSegName != "" {
+		args = append(args, "-init_seg_name", l.InitSegName)
+	}
+	if l.MediaSegName != "" {
+		args = append(args, "-media_seg_name", l.MediaSegName)
+	}
+	return args
+}
+
+// adaptationSets returns the -adaptation_sets value, output streams being
+// numbered video renditions first
+func (l *DASHLadder) adaptationSets() string {
+	var sets []string
+	if len(l.Renditions) > 0 {
+		sets = append(sets, "id=0,streams=v")
+	}
+
+	languages := make(map[string][]string)
+	for i, audio := range l.Audio {
+		languages[audio.Language] = append(languages[audio.Language], strconv.Itoa(len(l.Renditions)+i))
+	}
+	keys := make([]string, 0, len(languages))
+	for language := range languages {
+		keys = append(keys, language)
+	}
+	sort.Strings(keys)
+	for _, language := range keys {
+		sets = append(sets, "id="+strconv.Itoa(len(sets))+",streams="+strings.Join(languages[language], ","))
+	}
+	return strings.Join(sets, " ")
+}
+
+func boolArg(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+func (m *File) SetDASHLadder(v *DASHLadder) {
+	m.dashLadder = v
+}
+
+func (m *File) DASHLadder() *DASHLadder {
+	return m.dashLadder
+}
+
+func (m *File) ObtainDASHLadder() []string {
+	if m.dashLadder == nil {
+		return nil
+	}
+	return m.dashLadder.Args(m.metadata)
+}
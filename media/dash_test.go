@@ -0,0 +1,41 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDASHLadder(t *testing.T) {
+	t.Run("Should group renditions in adaptation sets", func(t *testing.T) {
+		ladder := &DASHLadder{
+			Renditions: []Rendition{{Height: 720, VideoBitRate: 2800000}, {Height: 360, VideoBitRate: 800000}},
+			Audio: []DASHAudioRendition{
+				{Language: "eng", Source: MapTypeIndex(0, StreamTypeAudio, 0), BitRate: 128000},
+				{Language: "spa", Source: MapTypeIndex(0, StreamTypeAudio, 1), BitRate: 128000},
+			},
+			SegmentDuration: 4,
+			UseTemplate:     true,
+			UseTimeline:     true,
+			HLSPlaylist:     true,
+		}
+		metadata := &Metadata{Streams: []Stream{{CodecType: CodecTypeVideo, Width: 1920, Height: 1080}}}
+
+		require.Equal(t, []string{
+			"-map", "0:v:0", "-filter:v:0", "scale=1280:720", "-c:v:0", "libx264", "-level:v:0", "3.1", "-b:v:0", "2800000",
+			"-map", "0:v:0", "-filter:v:1", "scale=640:360", "-c:v:1", "libx264", "-level:v:1", "3.0", "-b:v:1", "800000",
+			"-map", "0:a:0", "-c:a:0", "aac", "-b:a:0", "128000", "-metadata:s:a:0", "language=eng",
+			"-map", "0:a:1", "-c:a:1", "aac", "-b:a:1", "128000", "-metadata:s:a:1", "language=spa",
+			"-f", "dash", "-dash_segment_type", "mp4", "-adaptation_sets", "id=0,streams=v id=1,streams=2 id=2,streams=3",
+			"-seg_duration", "4", "-use_template", "1", "-use_timeline", "1", "-hls_playlist", "1",
+		}, ladder.Args(metadata))
+	})
+
+	t.Run("Should map the nth audio stream of the first input to renditions without a source", func(t *testing.T) {
+		ladder := &DASHLadder{Audio: []DASHAudioRendition{{Language: "eng"}, {Language: "spa"}}}
+		require.Equal(t, []string{
+			"-map", "0:a:0", "-c:a:0", "aac", "-metadata:s:a:0", "language=eng",
+			"-map", "0:a:1", "-c:a:1", "aac", "-metadata:s:a:1", "language=spa",
+		}, ladder.Args(&Metadata{})[:12])
+	})
+}
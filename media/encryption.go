@@ -0,0 +1,194 @@
+package media
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HLSKeySize is the size in bytes of AES-128 keys and IVs
+const HLSKeySize = 16
+
+// HLSKey is one AES-128 key of an encrypted HLS output
+type HLSKey struct {
+	Index int
+	Key   []byte
+	IV    []byte // nil when the segment sequence number is used as IV
+	URI   string // key URI written in the playlist
+	Path  string // key file read by ffmpeg
+}
+
+// IVHex Get the IV as written in the keyinfo file and the playlist, e.g. "0x0f..."
+func (k *HLSKey) IVHex() string {
+	if k.IV == nil {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(k.IV)
+}
+
+// NewHLSKey generates a random key and IV
+func NewHLSKey() (*HLSKey, error) {
+	key := &HLSKey{Key: make([]byte, HLSKeySize), IV: make([]byte, HLSKeySize)}
+	if _, err := rand.Read(key.Key); err != nil {
+		return nil, fmt.Errorf("cannot generate HLS key: %w", err)
+	}
+	if _, err := rand.Read(key.IV); err != nil {
+		return nil, fmt.Errorf("cannot generate HLS IV: %w", err)
+	}
+	return key, nil
+}
+
+// HLSEncryption is the AES-128 encryption of an HLS output. KeyURI and KeyPath
+// may contain "%d", replaced with the key index, and must do so when keys are
+// rotated so that earlier segments keep their key.
+type HLSEncryption struct {
+	KeyURI      string `json:"key_uri,omitempty"`       // the key file name when empty
+	KeyPath     string `json:"key_path,omitempty"`      // "<output>-%d.key" next to the output when empty
+	KeyInfoPath string `json:"key_info_path,omitempty"` // "<output>.keyinfo" next to the output when empty
+	SequenceIV  bool   `json:"sequence_iv,omitempty"`   // use the segment sequence number instead of a random IV
+
+	// RotationInterval enables -hls_flags periodic_rekey, writing a new key
+	// every interval while the job runs
+	RotationInterval time.Duration `json:"rotation_interval,omitempty"`
+
+	// OnKey is called with every new key before ffmpeg starts using it. The job
+	// is stopped when it returns an error.
+	OnKey func(*HLSKey) error `json:"-" yaml:"-"`
+
+	mu   sync.Mutex
+	next int
+}
+
+// Validate checks the key paths can hold more than one key when rotating
+func (e *HLSEncryption) Validate() error {
+	if e.RotationInterval <= 0 {
+		return nil
+	}
+	if e.KeyPath != "" && !strings.Contains(e.KeyPath, "%d") {
+		return errors.New("hls encryption: KeyPath must contain %d when keys are rotated")
+	}
+	if e.KeyURI != "" && !strings.Contains(e.KeyURI, "%d") {
+		return errors.New("hls encryption: KeyURI must contain %d when keys are rotated")
+	}
+	return nil
+}
+
+// KeyInfoFile Get the keyinfo path passed to -hls_key_info_file
+func (e *HLSEncryption) KeyInfoFile(outputPath string) string {
+	if e.KeyInfoPath != "" {
+		return e.KeyInfoPath
+	}
+	return outputPrefix(outputPath) + ".keyinfo"
+}
+
+func (e *HLSEncryption) keyPath(outputPath string, index int) string {
+	if e.KeyPath != "" {
+		return withIndex(e.KeyPath, index)
+	}
+	return outputPrefix(outputPath) + "-" + strconv.Itoa(index) + ".key"
+}
+
+func (e *HLSEncryption) keyURI(path string, index int) string {
+	if e.KeyURI != "" {
+		return withIndex(e.KeyURI, index)
+	}
+	return filepath.Base(path)
+}
+
+// Rotate generates the next key, writes its key file, reports it to OnKey and
+// then replaces the keyinfo file so ffmpeg picks it up at the next segment.
+func (e *HLSEncryption) Rotate(outputPath string) (*HLSKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key, err := NewHLSKey()
+	if err != nil {
+		return nil, err
+	}
+	if e.SequenceIV {
+		key.IV = nil
+	}
+	key.Index = e.next
+	key.Path = e.keyPath(outputPath, key.Index)
+	key.URI = e.keyURI(key.Path, key.Index)
+
+	if err := os.WriteFile(key.Path, key.Key, 0600); err != nil {
+		return nil, fmt.Errorf("cannot write HLS key: %w", err)
+	}
+	if e.OnKey != nil {
+		if err := e.OnKey(key); err != nil {
+			return nil, fmt.Errorf("cannot store HLS key %d: %w", key.Index, err)
+		}
+	}
+	if err := writeKeyInfo(e.KeyInfoFile(outputPath), key); err != nil {
+		return nil, err
+	}
+
+	e.next++
+	return key, nil
+}
+
+// Args Get the ffmpeg options of the encryption
+func (e *HLSEncryption) Args(outputPath string) []string {
+	args := []string{"-hls_key_info_file", e.KeyInfoFile(outputPath)}
+	if e.RotationInterval > 0 {
+		args = append(args, "-hls_flags", "periodic_rekey")
+	}
+	return args
+}
+
+// writeKeyInfo replaces the keyinfo file atomically, as ffmpeg may read it at
+// any segment boundary
+func writeKeyInfo(path string, key *HLSKey) error {
+	content := key.URI + "\n" + key.Path + "\n"
+	if iv := key.IVHex(); iv != "" {
+		content += iv + "\n"
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keyinfo-*")
+	if err != nil {
+		return fmt.Errorf("cannot write HLS keyinfo: %w", err)
+	}
+	_, err = tmp.WriteString(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cannot write HLS keyinfo: %w", err)
+	}
+	return nil
+}
+
+func outputPrefix(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+}
+
+func withIndex(pattern string, index int) string {
+	return strings.ReplaceAll(pattern, "%d", strconv.Itoa(index))
+}
+
+func (m *File) SetHLSEncryption(v *HLSEncryption) {
+	m.hlsEncryption = v
+}
+
+func (m *File) HLSEncryption() *HLSEncryption {
+	return m.hlsEncryption
+}
+
+func (m *File) ObtainHLSEncryption() []string {
+	if m.hlsEncryption != nil {
+		return m.hlsEncryption.Args(m.outputPath)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package media
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHLSEncryption(t *testing.T) {
+	t.Run("Should write the key and keyinfo files next to the output", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "stream.m3u8")
+		encryption := &HLSEncryption{}
+
+		key, err := encryption.Rotate(output)
+		require.NoError(t, err)
+		require.Len(t, key.Key, HLSKeySize)
+		require.Len(t, key.IV, HLSKeySize)
+		require.Equal(t, "stream-0.key", key.URI)
+
+		content, err := os.ReadFile(key.Path)
+		require.NoError(t, err)
+		require.Equal(t, key.Key, content)
+
+		keyInfo, err := os.ReadFile(encryption.KeyInfoFile(output))
+		require.NoError(t, err)
+		require.Equal(t, "stream-0.key\n"+key.Path+"\n"+key.IVHex()+"\n", string(keyInfo))
+	})
+
+	t.Run("Should report rotated keys and replace the keyinfo file", func(t *testing.T) {
+		dir := t.TempDir()
+		output := filepath.Join(dir, "stream.m3u8")
+		var keys []*HLSKey
+		encryption := &HLSEncryption{
+			KeyURI:           "https://keys.example.com/%d",
+			KeyPath:          filepath.Join(dir, "key-%d.bin"),
+			SequenceIV:       true,
+			RotationInterval: time.Minute,
+			OnKey: func(key *HLSKey) error {
+				keys = append(keys, key)
+				return nil
+			},
+		}
+		require.NoError(t, encryption.Validate())
+
+		for i := 0; i < 2; i++ {
+			_, err := encryption.Rotate(output)
+			require.NoError(t, err)
+		}
+		require.Len(t, keys, 2)
+		require.Equal(t, 1, keys[1].Index)
+		require.NotEqual(t, keys[0].Key, keys[1].Key)
+
+		keyInfo, err := os.ReadFile(filepath.Join(dir, "stream.keyinfo"))
+		require.NoError(t, err)
+		require.Equal(t, "https://keys.example.com/1\n"+filepath.Join(dir, "key-1.bin")+"\n", string(keyInfo))
+	})
+
+	t.Run("Should not switch keys the callback failed to store", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "stream.m3u8")
+		encryption := &HLSEncryption{OnKey: func(*HLSKey) error { return errors.New("unavailable") }}
+
+		_, err := encryption.Rotate(output)
+		require.Error(t, err)
+		_, err = os.Stat(encryption.KeyInfoFile(output))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Should require indexed key paths when rotating", func(t *testing.T) {
+		encryption := &HLSEncryption{KeyURI: "key.bin", RotationInterval: time.Minute}
+		require.Error(t, encryption.Validate())
+	})
+
+	t.Run("Should enable periodic rekey", func(t *testing.T) {
+		file := &File{}
+		file.SetOutputPath("out/stream.m3u8")
+		file.SetHLSEncryption(&HLSEncryption{RotationInterval: time.Minute})
+		require.Equal(t, []string{"-hls_key_info_file", "out/stream.keyinfo", "-hls_flags", "periodic_rekey"}, file.ObtainHLSEncryption())
+	})
+}
@@ -3,9 +3,12 @@ package media
 import (
 	"fmt"
 	"io"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/pkg/filtergraph"
 )
 
 type File struct {
@@ -23,6 +26,7 @@ type File struct {
 	minKeyframe           int
 	keyframeInterval      int
 	audioCodec            string
+	subtitleCodec         string
 	audioBitrate          string
 	audioChannels         int
 	audioVariableBitrate  bool
@@ -48,9 +52,11 @@ type File struct {
 	inputPipe             bool
 	inputPipeReader       io.ReadCloser
 	inputPipeWriter       io.Writer
+	inputs                []*Input
 	outputPipe            bool
 	outputPipeReader      io.Reader
 	outputPipeWriter      io.WriteCloser
+	outputs               []*File
 	movFlags              string
 	hideBanner            bool
 	outputPath            string
@@ -64,23 +70,34 @@ type File struct {
 	hlsSegmentDuration    int
 	hlsMasterPlaylistName string
 	hlsSegmentFilename    string
+	hlsLadder             *HLSLadder
+	dashLadder            *DASHLadder
 	httpMethod            string
 	httpKeepAlive         bool
 	hwaccel               string
 	streamIds             map[int]string
+	maps                  []StreamSpecifier
+	subtitleTracks        []SubtitleTrack
+	burnSubtitles         *BurnSubtitles
 	metadata              *Metadata
 	videoFilter           string
 	audioFilter           string
+	filterGraph           *filtergraph.Graph
 	skipVideo             bool
 	skipAudio             bool
 	compressionLevel      int
 	mapMetadata           string
 	tags                  map[string]string
 	encryptionKey         string
+	hlsEncryption         *HLSEncryption
 	bFrame                int
 	pixFmt                string
 	rawInputArgs          []string
 	rawOutputArgs         []string
+	fpsMode               string
+	ffmpegVersion         goffmpeg.Version
+	optionRegistry        *OptionRegistry
+	optionValues          map[string]string
 }
 
 /*** SETTERS ***/
@@ -97,6 +114,11 @@ func (m *File) SetFilter(v string) {
 	m.SetVideoFilter(v)
 }
 
+// SetFilterGraph sets a -filter_complex graph. Its outputs are mapped to the output.
+func (m *File) SetFilterGraph(v *filtergraph.Graph) {
+	m.filterGraph = v
+}
+
 func (m *File) SetAspect(v string) {
 	m.aspect = v
 }
@@ -133,6 +155,20 @@ func (m *File) SetFrameRate(v int) {
 	m.frameRate = v
 }
 
+// SetFpsMode sets the frame sync mode: passthrough, cfr, vfr, drop or auto
+func (m *File) SetFpsMode(v string) {
+	m.fpsMode = v
+}
+
+// SetFFmpegVersion sets the ffmpeg version flags are chosen for, on the file
+// and its outputs. Flags supported by every version are used while it is unset.
+func (m *File) SetFFmpegVersion(v goffmpeg.Version) {
+	m.ffmpegVersion = v
+	for _, output := range m.outputs {
+		output.SetFFmpegVersion(v)
+	}
+}
+
 func (m *File) SetAudioRate(v int) {
 	m.audioRate = v
 }
@@ -157,6 +193,10 @@ func (m *File) SetAudioCodec(v string) {
 	m.audioCodec = v
 }
 
+func (m *File) SetSubtitleCodec(v string) {
+	m.subtitleCodec = v
+}
+
 func (m *File) SetAudioBitRate(v string) {
 	m.audioBitrate = v
 }
@@ -239,6 +279,12 @@ func (m *File) SetInputPath(val string) {
 	m.inputPath = val
 }
 
+// AddInput appends an input after the main one and returns its ffmpeg input index
+func (m *File) AddInput(in *Input) int {
+	m.inputs = append(m.inputs, in)
+	return m.inputIndexOffset() + len(m.inputs) - 1
+}
+
 func (m *File) SetInputPipe(val bool) {
 	m.inputPipe = val
 }
@@ -263,6 +309,13 @@ func (m *File) SetOutputPipeWriter(w io.WriteCloser) {
 	m.outputPipeWriter = w
 }
 
+// AddOutput appends an output written by the same ffmpeg invocation and returns
+// its output index. Only the output options of o are used.
+func (m *File) AddOutput(o *File) int {
+	m.outputs = append(m.outputs, o)
+	return len(m.outputs)
+}
+
 func (m *File) SetMovFlags(val string) {
 	m.movFlags = val
 }
@@ -331,6 +384,11 @@ func (m *File) SetStreamIds(val map[int]string) {
 	m.streamIds = val
 }
 
+// AddMap appends -map options, in order, to the output
+func (m *File) AddMap(specs ...StreamSpecifier) {
+	m.maps = append(m.maps, specs...)
+}
+
 func (m *File) SetSkipVideo(val bool) {
 	m.skipVideo = val
 }
@@ -382,6 +440,10 @@ func (m *File) AudioFilter() string {
 	return m.audioFilter
 }
 
+func (m *File) FilterGraph() *filtergraph.Graph {
+	return m.filterGraph
+}
+
 func (m *File) Aspect() string {
 	return m.aspect
 }
@@ -418,6 +480,14 @@ func (m *File) FrameRate() int {
 	return m.frameRate
 }
 
+func (m *File) FpsMode() string {
+	return m.fpsMode
+}
+
+func (m *File) FFmpegVersion() goffmpeg.Version {
+	return m.ffmpegVersion
+}
+
 func (m *File) GetPixFmt() string {
 	return m.pixFmt
 }
@@ -442,6 +512,10 @@ func (m *File) AudioCodec() string {
 	return m.audioCodec
 }
 
+func (m *File) SubtitleCodec() string {
+	return m.subtitleCodec
+}
+
 func (m *File) AudioBitrate() string {
 	return m.audioBitrate
 }
@@ -526,6 +600,30 @@ func (m *File) InputPath() string {
 	return m.inputPath
 }
 
+// Inputs returns the inputs added after the main one
+func (m *File) Inputs() []*Input {
+	return m.inputs
+}
+
+// InputMetadata returns the metadata of the input at the ffmpeg input index
+func (m *File) InputMetadata(index int) *Metadata {
+	offset := m.inputIndexOffset()
+	if index < offset {
+		return m.metadata
+	}
+	if index-offset >= len(m.inputs) {
+		return nil
+	}
+	return m.inputs[index-offset].Metadata
+}
+
+func (m *File) inputIndexOffset() int {
+	if m.inputPath != "" || m.inputPipe {
+		return 1
+	}
+	return 0
+}
+
 func (m *File) InputPipe() bool {
 	return m.inputPipe
 }
@@ -550,6 +648,27 @@ func (m *File) OutputPipeWriter() io.WriteCloser {
 	return m.outputPipeWriter
 }
 
+// Outputs returns the outputs added after the main one
+func (m *File) Outputs() []*File {
+	return m.outputs
+}
+
+// OutputTargets returns the destination of every output, main one first
+func (m *File) OutputTargets() []string {
+	targets := []string{m.outputTarget()}
+	for _, output := range m.outputs {
+		targets = append(targets, output.outputTarget())
+	}
+	return targets
+}
+
+func (m *File) outputTarget() string {
+	if m.outputPipe {
+		return "pipe:1"
+	}
+	return m.outputPath
+}
+
 func (m *File) MovFlags() string {
 	return m.movFlags
 }
@@ -614,6 +733,10 @@ func (m *File) StreamIds() map[int]string {
 	return m.streamIds
 }
 
+func (m *File) Maps() []StreamSpecifier {
+	return m.maps
+}
+
 func (m *File) SkipVideo() bool {
 	return m.skipVideo
 }
@@ -655,89 +778,28 @@ func (m *File) RawOutputArgs() []string {
 }
 
 /** OPTS **/
+
 func (m *File) ToStrCommand() []string {
-	var strCommand []string
-
-	opts := []string{
-		"SeekTimeInput",
-		"SeekUsingTsInput",
-		"NativeFramerateInput",
-		"DurationInput",
-		"RtmpLive",
-		"InputInitialOffset",
-		"HardwareAcceleration",
-		"RawInputArgs",
-		"InputPath",
-		"InputPipe",
-		"HideBanner",
-		"Aspect",
-		"Resolution",
-		"FrameRate",
-		"AudioRate",
-		"VideoCodec",
-		"Vframes",
-		"VideoBitRate",
-		"VideoBitRateTolerance",
-		"VideoMaxBitRate",
-		"VideoMinBitRate",
-		"VideoProfile",
-		"SkipVideo",
-		"AudioCodec",
-		"AudioBitRate",
-		"AudioChannels",
-		"AudioProfile",
-		"SkipAudio",
-		"CRF",
-		"QScale",
-		"Strict",
-		"SingleFile",
-		"BufferSize",
-		"MuxDelay",
-		"Threads",
-		"KeyframeInterval",
-		"Preset",
-		"PixFmt",
-		"Tune",
-		"Target",
-		"SeekTime",
-		"Duration",
-		"CopyTs",
-		"StreamIds",
-		"MovFlags",
-		"RawOutputArgs",
-		"OutputFormat",
-		"OutputPipe",
-		"HlsListSize",
-		"HlsSegmentDuration",
-		"HlsPlaylistType",
-		"HlsMasterPlaylistName",
-		"HlsSegmentFilename",
-		"AudioFilter",
-		"VideoFilter",
-		"HttpMethod",
-		"HttpKeepAlive",
-		"CompressionLevel",
-		"MapMetadata",
-		"Tags",
-		"EncryptionKey",
-		"OutputPath",
-		"Bframe",
-	}
-
-	for _, name := range opts {
-		opt := reflect.ValueOf(m).MethodByName(fmt.Sprintf("Obtain%s", name))
-		if (opt != reflect.Value{}) {
-			result := opt.Call([]reflect.Value{})
-
-			if val, ok := result[0].Interface().([]string); ok {
-				strCommand = append(strCommand, val...)
-			}
+	registry := m.OptionRegistry()
+	strCommand := registry.args(m, PlacementGlobal)
+	strCommand = append(strCommand, registry.args(m, PlacementInput)...)
+	strCommand = append(strCommand, m.ToOutputStrCommand()...)
+	for _, output := range m.outputs {
+		// outputs without a registry of their own share the one of the file
+		outputRegistry := registry
+		if output.optionRegistry != nil {
+			outputRegistry = output.optionRegistry
 		}
+		strCommand = append(strCommand, outputRegistry.args(output, PlacementOutput)...)
 	}
-
 	return strCommand
 }
 
+// ToOutputStrCommand returns only the output options and destination of the File
+func (m *File) ToOutputStrCommand() []string {
+	return m.OptionRegistry().args(m, PlacementOutput)
+}
+
 func (m *File) ObtainAudioFilter() []string {
 	if m.audioFilter != "" {
 		return []string{"-af", m.audioFilter}
@@ -746,8 +808,39 @@ func (m *File) ObtainAudioFilter() []string {
 }
 
 func (m *File) ObtainVideoFilter() []string {
+	var filters []string
 	if m.videoFilter != "" {
-		return []string{"-vf", m.videoFilter}
+		filters = append(filters, m.videoFilter)
+	}
+	if m.burnSubtitles != nil {
+		filters = append(filters, m.burnSubtitles.Filter())
+	}
+	if len(filters) > 0 {
+		return []string{"-vf", strings.Join(filters, ",")}
+	}
+	return nil
+}
+
+func (m *File) ObtainFilterGraph() []string {
+	if m.filterGraph == nil {
+		return nil
+	}
+	args := []string{"-filter_complex", m.filterGraph.String()}
+	for _, label := range m.filterGraph.OutputLabels() {
+		args = append(args, "-map", label)
+	}
+	return args
+}
+
+// ValidateFilterGraph checks the filter graphs of the File and its outputs
+func (m *File) ValidateFilterGraph() error {
+	for _, file := range append([]*File{m}, m.outputs...) {
+		if file.filterGraph == nil {
+			continue
+		}
+		if err := file.filterGraph.Validate(); err != nil {
+			return fmt.Errorf("invalid filter graph: %w", err)
+		}
 	}
 	return nil
 }
@@ -790,6 +883,14 @@ func (m *File) ObtainInputPipe() []string {
 	return nil
 }
 
+func (m *File) ObtainInputs() []string {
+	var result []string
+	for _, in := range m.inputs {
+		result = append(result, in.ToStrCommand()...)
+	}
+	return result
+}
+
 func (m *File) ObtainOutputPipe() []string {
 	if m.outputPipe {
 		return []string{"pipe:1"}
@@ -846,6 +947,18 @@ func (m *File) ObtainFrameRate() []string {
 	return nil
 }
 
+// ObtainFpsMode uses -fps_mode from ffmpeg 5.1, and -vsync before it or when
+// the version is unknown
+func (m *File) ObtainFpsMode() []string {
+	if m.fpsMode == "" {
+		return nil
+	}
+	if !m.ffmpegVersion.IsZero() && m.ffmpegVersion.AtLeast(5, 1, 0) {
+		return []string{"-fps_mode", m.fpsMode}
+	}
+	return []string{"-vsync", m.fpsMode}
+}
+
 func (m *File) ObtainAudioRate() []string {
 	if m.audioRate != 0 {
 		return []string{"-ar", fmt.Sprintf("%d", m.audioRate)}
@@ -874,6 +987,13 @@ func (m *File) ObtainAudioCodec() []string {
 	return nil
 }
 
+func (m *File) ObtainSubtitleCodec() []string {
+	if m.subtitleCodec != "" {
+		return []string{"-c:s", m.subtitleCodec}
+	}
+	return nil
+}
+
 func (m *File) ObtainAudioBitRate() []string {
 	switch {
 	case !m.audioVariableBitrate && m.audioBitrate != "":
@@ -1080,7 +1200,10 @@ func (m *File) ObtainInputInitialOffset() []string {
 }
 
 func (m *File) ObtainHlsListSize() []string {
-	return []string{"-hls_list_size", fmt.Sprintf("%d", m.hlsListSize)}
+	if m.isHls() {
+		return []string{"-hls_list_size", fmt.Sprintf("%d", m.hlsListSize)}
+	}
+	return nil
 }
 
 func (m *File) ObtainHlsSegmentDuration() []string {
@@ -1101,7 +1224,7 @@ func (m *File) ObtainHlsMasterPlaylistName() []string {
 
 func (m *File) ObtainHlsSegmentFilename() []string {
 	if m.hlsSegmentFilename != "" {
-		return []string{"-hls_segment_filename", "%s", m.hlsSegmentFilename}
+		return []string{"-hls_segment_filename", m.hlsSegmentFilename}
 	} else {
 		return nil
 	}
@@ -1149,15 +1272,29 @@ func (m *File) ObtainSkipAudio() []string {
 
 func (m *File) ObtainStreamIds() []string {
 	if len(m.streamIds) != 0 {
+		indexes := make([]int, 0, len(m.streamIds))
+		for i := range m.streamIds {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+
 		result := []string{}
-		for i, val := range m.streamIds {
-			result = append(result, []string{"-streamid", fmt.Sprintf("%d:%s", i, val)}...)
+		for _, i := range indexes {
+			result = append(result, []string{"-streamid", fmt.Sprintf("%d:%s", i, m.streamIds[i])}...)
 		}
 		return result
 	}
 	return nil
 }
 
+func (m *File) ObtainMaps() []string {
+	var result []string
+	for _, spec := range m.maps {
+		result = append(result, "-map", spec.String())
+	}
+	return result
+}
+
 func (m *File) ObtainCompressionLevel() []string {
 	if m.compressionLevel != 0 {
 		return []string{"-compression_level", fmt.Sprintf("%d", m.compressionLevel)}
@@ -1189,9 +1326,15 @@ func (m *File) ObtainBframe() []string {
 
 func (m *File) ObtainTags() []string {
 	if len(m.tags) != 0 {
+		keys := make([]string, 0, len(m.tags))
+		for key := range m.tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
 		result := []string{}
-		for key, val := range m.tags {
-			result = append(result, []string{"-metadata", fmt.Sprintf("%s=%s", key, val)}...)
+		for _, key := range keys {
+			result = append(result, []string{"-metadata", fmt.Sprintf("%s=%s", key, m.tags[key])}...)
 		}
 		return result
 	}
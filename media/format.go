@@ -61,6 +61,9 @@ type (
 const (
 	CodecTypeVideo       CodecType   = "video"
 	CodecTypeAudio       CodecType   = "audio"
+	CodecTypeSubtitle    CodecType   = "subtitle"
+	CodecTypeData        CodecType   = "data"
+	CodecTypeAttachment  CodecType   = "attachment"
 	OrientationLandscape Orientation = "landscape"
 	OrientationPortrait  Orientation = "portrait"
 )
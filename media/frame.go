@@ -0,0 +1,123 @@
+package media
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NoPTS is the PTS and DTS of frames and packets without a timestamp
+const NoPTS int64 = math.MinInt64
+
+// Frame is a decoded frame reported by ffprobe -show_frames
+type Frame struct {
+	MediaType           CodecType     `json:"media_type"`
+	StreamIndex         int           `json:"stream_index"`
+	KeyFrame            bool          `json:"-"`
+	PTS                 int64         `json:"pts"`
+	PTSTimeStr          string        `json:"pts_time"`
+	PTSTime             time.Duration `json:"-"`
+	DTS                 int64         `json:"pkt_dts"`
+	DTSTimeStr          string        `json:"pkt_dts_time"`
+	DTSTime             time.Duration `json:"-"`
+	BestEffortTimestamp int64         `json:"best_effort_timestamp"`
+	DurationStr         string        `json:"duration_time"`
+	Duration            time.Duration `json:"-"`
+	PosStr              string        `json:"pkt_pos"`
+	Pos                 int64         `json:"-"` // byte offset of the packet, -1 when unknown
+	SizeStr             string        `json:"pkt_size"`
+	Size                int           `json:"-"`
+	Width               int           `json:"width"`
+	Height              int           `json:"height"`
+	PixFmt              string        `json:"pix_fmt"`
+	PictType            string        `json:"pict_type"` // I, P or B
+	InterlacedFrame     bool          `json:"-"`
+	SampleFmt           string        `json:"sample_fmt"`
+	NbSamples           int           `json:"nb_samples"`
+	Channels            int           `json:"channels"`
+	ChannelLayout       string        `json:"channel_layout"`
+}
+
+func (f *Frame) UnmarshalJSON(bytes []byte) error {
+	type Alias Frame
+	frame := &Alias{PTS: NoPTS, DTS: NoPTS, BestEffortTimestamp: NoPTS}
+	if err := json.Unmarshal(bytes, frame); err != nil {
+		return err
+	}
+	var flags struct {
+		KeyFrame           int    `json:"key_frame"`
+		InterlacedFrame    int    `json:"interlaced_frame"`
+		PktDurationTimeStr string `json:"pkt_duration_time"` // before ffmpeg 5.0
+	}
+	if err := json.Unmarshal(bytes, &flags); err != nil {
+		return err
+	}
+
+	*f = Frame(*frame)
+	if f.DurationStr == "" {
+		f.DurationStr = flags.PktDurationTimeStr
+	}
+	f.KeyFrame = flags.KeyFrame == 1
+	f.InterlacedFrame = flags.InterlacedFrame == 1
+	f.PTSTime = parseSeconds(f.PTSTimeStr)
+	f.DTSTime = parseSeconds(f.DTSTimeStr)
+	f.Duration = parseSeconds(f.DurationStr)
+	f.Pos = parsePos(f.PosStr)
+	f.Size, _ = strconv.Atoi(f.SizeStr)
+	return nil
+}
+
+// Packet is a demuxed packet reported by ffprobe -show_packets
+type Packet struct {
+	CodecType   CodecType     `json:"codec_type"`
+	StreamIndex int           `json:"stream_index"`
+	PTS         int64         `json:"pts"`
+	PTSTimeStr  string        `json:"pts_time"`
+	PTSTime     time.Duration `json:"-"`
+	DTS         int64         `json:"dts"`
+	DTSTimeStr  string        `json:"dts_time"`
+	DTSTime     time.Duration `json:"-"`
+	DurationStr string        `json:"duration_time"`
+	Duration    time.Duration `json:"-"`
+	SizeStr     string        `json:"size"`
+	Size        int           `json:"-"`
+	PosStr      string        `json:"pos"`
+	Pos         int64         `json:"-"` // byte offset in the input, -1 when unknown
+	Flags       string        `json:"flags"`
+	KeyFrame    bool          `json:"-"`
+}
+
+func (p *Packet) UnmarshalJSON(bytes []byte) error {
+	type Alias Packet
+	packet := &Alias{PTS: NoPTS, DTS: NoPTS}
+	if err := json.Unmarshal(bytes, packet); err != nil {
+		return err
+	}
+	*p = Packet(*packet)
+	p.PTSTime = parseSeconds(p.PTSTimeStr)
+	p.DTSTime = parseSeconds(p.DTSTimeStr)
+	p.Duration = parseSeconds(p.DurationStr)
+	p.Size, _ = strconv.Atoi(p.SizeStr)
+	p.Pos = parsePos(p.PosStr)
+	p.KeyFrame = strings.HasPrefix(p.Flags, "K")
+	return nil
+}
+
+// parseSeconds parses the *_time values of ffprobe, zero when missing
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
+
+func parsePos(s string) int64 {
+	pos, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return pos
+}
@@ -0,0 +1,305 @@
+package media
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultHLSMasterPlaylist is the master playlist name of an HLSLadder
+const DefaultHLSMasterPlaylist = "master.m3u8"
+
+// HLSAudioRendition is an alternate audio of an HLS ladder
+type HLSAudioRendition struct {
+	GroupID  string          `json:"group_id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Language string          `json:"language,omitempty"`
+	Default  bool            `json:"default,omitempty"`
+	Source   StreamSpecifier `json:"source,omitempty"`   // the nth audio stream of input 0 when unset, n being the rendition index
+	Codec    string          `json:"codec,omitempty"`    // aac when empty
+	BitRate  int             `json:"bit_rate,omitempty"` // bits per second
+}
+
+// HLSSubtitleRendition is a WebVTT subtitle rendition of an HLS ladder
+type HLSSubtitleRendition struct {
+	GroupID  string          `json:"group_id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Language string          `json:"language,omitempty"`
+	Default  bool            `json:"default,omitempty"`
+	Forced   bool            `json:"forced,omitempty"`
+	Source   StreamSpecifier `json:"source,omitempty"` // the nth subtitle stream of input 0 when unset, n being the rendition index
+}
+
+// HLSLadder is a multi-variant HLS output. Each rendition is a video variant;
+// renditions with AudioGroup reference the alternate audio of that group instead
+// of muxing their own.
+type HLSLadder struct {
+	Renditions      []HLSRendition         `json:"renditions,omitempty"`
+	Audio           []HLSAudioRendition    `json:"audio,omitempty"`
+	Subtitles       []HLSSubtitleRendition `json:"subtitles,omitempty"`
+	SegmentDuration int                    `json:"segment_duration,omitempty"`
+	PlaylistType    string                 `json:"playlist_type,omitempty"`    // vod or event
+	MasterPlaylist  string                 `json:"master_playlist,omitempty"`  // DefaultHLSMasterPlaylist when empty
+	SegmentFilename string                 `json:"segment_filename,omitempty"` // segment pattern, "%v_%03d.ts" next to the playlists when empty
+}
+
+// HLSRendition is a video variant of an HLSLadder
+type HLSRendition struct {
+	Rendition
+	AudioGroup    string `json:"audio_group,omitempty"`
+	SubtitleGroup string `json:"subtitle_group,omitempty"`
+}
+
+// vttURI Get the WebVTT playlist the hls muxer writes for the variant playlist uri
+func vttURI(uri string) string {
+	return strings.TrimSuffix(uri, filepath.Ext(uri)) + "_vtt.m3u8"
+}
+
+func (l *HLSLadder) masterPlaylist() string {
+	if l.MasterPlaylist == "" {
+		return DefaultHLSMasterPlaylist
+	}
+	return l.MasterPlaylist
+}
+
+// variantNameReplacer removes the separators of -var_stream_map from names
+var variantNameReplacer = strings.NewReplacer(" ", "_", ",", "_")
+
+// variantName returns the %v value of the nth entry of the -var_stream_map
+func variantName(name string, index int) string {
+	if name != "" {
+		return variantNameReplacer.Replace(name)
+	}
+	return strconv.Itoa(index)
+}
+
+// Args returns the output options of the ladder, before the variant playlist
+// pattern which must contain %v, e.g. "out/stream_%v.m3u8"
+func (l *HLSLadder) Args(metadata *Metadata, outputPath string) []string {
+	var args, streamMap []string
+	audioIndex := 0
+
+	for i, rendition := range l.Renditions {
+		r := rendition.Rendition.resolve(metadata)
+		index := strconv.Itoa(i)
+		args = append(args,
+			"-map", MapTypeIndex(0, StreamTypeVideo, 0).String(),
+			"-filter:v:"+index, r.scaleFilter(),
+			"-c:v:"+index, r.VideoCodec,
+		)
+		if r.VideoProfile != "" {
+			args = append(args, "-profile:v:"+index, r.VideoProfile)
+		}
+		args = append(args, r.levelArgs(index)...)
+		if r.VideoBitRate > 0 {
+			args = append(args, "-b:v:"+index, strconv.Itoa(r.VideoBitRate))
+		}
+		if r.MaxRate > 0 {
+			args = append(args, "-maxrate:v:"+index, strconv.Itoa(r.MaxRate))
+		}
+		if r.BufSize > 0 {
+			args = append(args, "-bufsize:v:"+index, strconv.Itoa(r.BufSize))
+		}
+
+		entry := "v:" + index
+		if rendition.AudioGroup == "" && r.AudioBitRate > 0 {
+			audio := strconv.Itoa(audioIndex)
+			args = append(args,
+				"-map", MapTypeIndex(0, StreamTypeAudio, 0).String(),
+				"-c:a:"+audio, r.AudioCodec,
+				"-b:a:"+audio, strconv.Itoa(r.AudioBitRate),
+			)
+			entry += ",a:" + audio
+			audioIndex++
+		} else if rendition.AudioGroup != "" {
+			entry += ",agroup:" + rendition.AudioGroup
+		}
+		if rendition.SubtitleGroup != "" {
+			entry += ",sgroup:" + rendition.SubtitleGroup
+		}
+		if r.Name != "" {
+			entry += ",name:" + variantName(r.Name, i)
+		}
+		streamMap = append(streamMap, entry)
+	}
+
+	for i, audio := range l.Audio {
+		index := strconv.Itoa(audioIndex)
+		codec := audio.Codec
+		if codec == "" {
+			codec = "aac"
+		}
+		args = append(args, "-map", audio.Source.orDefault(StreamTypeAudio, i).String(), "-c:a:"+index, codec)
+		if audio.BitRate > 0 {
+			args = append(args, "-b:a:"+index, strconv.Itoa(audio.BitRate))
+		}
+
+		entry := "a:" + index + ",agroup:" + audio.GroupID
+		if audio.Language != "" {
+			entry += ",language:" + audio.Language
+		}
+		if audio.Name != "" {
+			entry += ",name:" + variantName(audio.Name, 0)
+		}
+		if audio.Default {
+			entry += ",default:yes"
+		}
+		streamMap = append(streamMap, entry)
+		audioIndex++
+	}
+
+	for i, subtitle := range l.Subtitles {
+		index := strconv.Itoa(i)
+		args = append(args, "-map", subtitle.Source.orDefault(StreamTypeSubtitle, i).String(), "-c:s:"+index, "webvtt")
+
+		entry := "s:" + index + ",sgroup:" + subtitle.GroupID
+		if subtitle.Language != "" {
+			entry += ",language:" + subtitle.Language
+		}
+		if subtitle.Name != "" {
+			entry += ",name:" + variantName(subtitle.Name, 0)
+		}
+		streamMap = append(streamMap, entry)
+	}
+
+	args = append(args, "-f", "hls")
+	if l.SegmentDuration > 0 {
+		args = append(args, "-hls_time", strconv.Itoa(l.SegmentDuration))
+	}
+	if l.PlaylistType != "" {
+		args = append(args, "-hls_playlist_type", l.PlaylistType)
+	}
+	segmentFilename := l.SegmentFilename
+	if segmentFilename == "" {
+		segmentFilename = filepath.Join(filepath.Dir(outputPath), "%v_%03d.ts")
+	}
+	args = append(args,
+		"-hls_segment_filename", segmentFilename,
+		"-var_stream_map", strings.Join(streamMap, " "),
+	)
+	return args
+}
+
+// MasterPlaylistContent returns the master playlist of the ladder. Variant URIs
+// are the base name of outputPath with %v replaced by the variant name, and
+// "_vtt" added before the extension for subtitles, as the hls muxer writes the
+// WebVTT playlist of a variant next to its main one.
+func (l *HLSLadder) MasterPlaylistContent(metadata *Metadata, outputPath string) string {
+	pattern := filepath.Base(outputPath)
+	uri := func(name string, index int) string {
+		return strings.ReplaceAll(pattern, "%v", variantName(name, index))
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	groupBitRate := make(map[string]int)
+	groupCodec := make(map[string]string)
+	for i, audio := range l.Audio {
+		index := len(l.Renditions) + i
+		fmt.Fprintf(&playlist, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=%q,NAME=%q%s,DEFAULT=%s,AUTOSELECT=YES,URI=%q\n",
+			audio.GroupID, mediaName(audio.Name, audio.Language, index), languageAttribute(audio.Language),
+			yesNo(audio.Default), uri(audio.Name, index))
+		if audio.BitRate > groupBitRate[audio.GroupID] {
+			groupBitRate[audio.GroupID] = audio.BitRate
+		}
+		if groupCodec[audio.GroupID] == "" {
+			groupCodec[audio.GroupID] = audio.Codec
+			if audio.Codec == "" {
+				groupCodec[audio.GroupID] = "aac"
+			}
+		}
+	}
+
+	for i, subtitle := range l.Subtitles {
+		index := len(l.Renditions) + len(l.Audio) + i
+		forced := ""
+		if subtitle.Forced {
+			forced = ",FORCED=YES"
+		}
+		fmt.Fprintf(&playlist, "#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=%q,NAME=%q%s,DEFAULT=%s,AUTOSELECT=YES%s,URI=%q\n",
+			subtitle.GroupID, mediaName(subtitle.Name, subtitle.Language, index), languageAttribute(subtitle.Language),
+			yesNo(subtitle.Default), forced, vttURI(uri(subtitle.Name, index)))
+	}
+
+	for i, rendition := range l.Renditions {
+		r := rendition.Rendition.resolve(metadata)
+
+		audioBitRate, audioCodec := r.AudioBitRate, ""
+		if rendition.AudioGroup != "" {
+			audioBitRate, audioCodec = groupBitRate[rendition.AudioGroup], groupCodec[rendition.AudioGroup]
+		} else if r.AudioBitRate > 0 {
+			audioCodec = r.AudioCodec
+		}
+		peak, average := r.Bandwidth(audioBitRate)
+
+		fmt.Fprintf(&playlist, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d", peak, average)
+		if r.Width > 0 && r.Height > 0 {
+			fmt.Fprintf(&playlist, ",RESOLUTION=%dx%d", r.Width, r.Height)
+		}
+		fmt.Fprintf(&playlist, ",CODECS=%q", r.Codecs(audioCodec))
+		if rendition.AudioGroup != "" {
+			fmt.Fprintf(&playlist, ",AUDIO=%q", rendition.AudioGroup)
+		}
+		if rendition.SubtitleGroup != "" {
+			fmt.Fprintf(&playlist, ",SUBTITLES=%q", rendition.SubtitleGroup)
+		}
+		playlist.WriteString("\n" + uri(r.Name, i) + "\n")
+	}
+
+	return playlist.String()
+}
+
+// WriteMasterPlaylist writes the master playlist next to the variant playlists
+func (l *HLSLadder) WriteMasterPlaylist(metadata *Metadata, outputPath string) error {
+	path := filepath.Join(filepath.Dir(outputPath), l.masterPlaylist())
+	return os.WriteFile(path, []byte(l.MasterPlaylistContent(metadata, outputPath)), 0o644)
+}
+
+func mediaName(name, language string, index int) string {
+	if name != "" {
+		return name
+	}
+	if language != "" {
+		return language
+	}
+	return strconv.Itoa(index)
+}
+
+func languageAttribute(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf(",LANGUAGE=%q", language)
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "YES"
+	}
+	return "NO"
+}
+
+func (m *File) SetHLSLadder(v *HLSLadder) {
+	m.hlsLadder = v
+}
+
+func (m *File) HLSLadder() *HLSLadder {
+	return m.hlsLadder
+}
+
+func (m *File) ObtainHLSLadder() []string {
+	if m.hlsLadder == nil {
+		return nil
+	}
+	return m.hlsLadder.Args(m.metadata, m.outputPath)
+}
+
+// isHls reports whether the output is written with the hls muxer
+func (m *File) isHls() bool {
+	return m.outputFormat == "hls" || m.hlsLadder != nil || m.hlsSegmentDuration != 0 ||
+		m.hlsPlaylistType != "" || m.hlsMasterPlaylistName != "" || m.hlsSegmentFilename != "" ||
+		strings.EqualFold(filepath.Ext(m.outputPath), ".m3u8")
+}
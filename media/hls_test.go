@@ -0,0 +1,76 @@
+package media
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func hlsLadder() *HLSLadder {
+	return &HLSLadder{
+		Renditions: []HLSRendition{
+			{Rendition: Rendition{Name: "1080p", Height: 1080, VideoBitRate: 5000000, MaxRate: 5350000}, AudioGroup: "aud", SubtitleGroup: "subs"},
+			{Rendition: Rendition{Name: "720p", Height: 720, VideoBitRate: 2800000, VideoProfile: "main"}, AudioGroup: "aud", SubtitleGroup: "subs"},
+		},
+		Audio: []HLSAudioRendition{
+			{GroupID: "aud", Name: "English", Language: "eng", Default: true, Source: MapTypeIndex(0, StreamTypeAudio, 0), BitRate: 128000},
+		},
+		Subtitles: []HLSSubtitleRendition{
+			{GroupID: "subs", Name: "English CC", Language: "eng", Source: MapTypeIndex(0, StreamTypeSubtitle, 0)},
+		},
+		SegmentDuration: 6,
+		PlaylistType:    "vod",
+	}
+}
+
+func TestHLSLadder(t *testing.T) {
+	metadata := &Metadata{Streams: []Stream{{CodecType: CodecTypeVideo, Width: 1920, Height: 1080}}}
+
+	t.Run("Should build the variant stream map", func(t *testing.T) {
+		args := hlsLadder().Args(metadata, "out/%v.m3u8")
+		require.Equal(t, []string{
+			"-map", "0:v:0", "-filter:v:0", "scale=1920:1080", "-c:v:0", "libx264", "-level:v:0", "4.0",
+			"-b:v:0", "5000000", "-maxrate:v:0", "5350000",
+			"-map", "0:v:0", "-filter:v:1", "scale=1280:720", "-c:v:1", "libx264", "-profile:v:1", "main", "-level:v:1", "3.1",
+			"-b:v:1", "2800000",
+			"-map", "0:a:0", "-c:a:0", "aac", "-b:a:0", "128000",
+			"-map", "0:s:0", "-c:s:0", "webvtt",
+			"-f", "hls", "-hls_time", "6", "-hls_playlist_type", "vod",
+			"-hls_segment_filename", "out/%v_%03d.ts",
+			"-var_stream_map", "v:0,agroup:aud,sgroup:subs,name:1080p v:1,agroup:aud,sgroup:subs,name:720p " +
+				"a:0,agroup:aud,language:eng,name:English,default:yes s:0,sgroup:subs,language:eng,name:English_CC",
+		}, args)
+	})
+
+	t.Run("Should write the master playlist with bandwidth, resolution and codecs", func(t *testing.T) {
+		require.Equal(t, `#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,URI="English.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English CC",LANGUAGE="eng",DEFAULT=NO,AUTOSELECT=YES,URI="English_CC_vtt.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=5478000,AVERAGE-BANDWIDTH=5128000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="aud",SUBTITLES="subs"
+1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2928000,AVERAGE-BANDWIDTH=2928000,RESOLUTION=1280x720,CODECS="avc1.4D401F,mp4a.40.2",AUDIO="aud",SUBTITLES="subs"
+720p.m3u8
+`, hlsLadder().MasterPlaylistContent(metadata, "out/%v.m3u8"))
+	})
+
+	t.Run("Should map the nth stream of the first input to renditions without a source", func(t *testing.T) {
+		ladder := &HLSLadder{
+			Renditions: []HLSRendition{{Rendition: Rendition{Height: 720, VideoBitRate: 2800000}, AudioGroup: "aud"}},
+			Audio:      []HLSAudioRendition{{GroupID: "aud", Name: "English"}, {GroupID: "aud", Name: "French"}},
+			Subtitles:  []HLSSubtitleRendition{{GroupID: "subs", Name: "English"}},
+		}
+		args := strings.Join(ladder.Args(metadata, "out/%v.m3u8"), " ")
+		require.Contains(t, args, "-map 0:a:0 -c:a:0 aac -map 0:a:1 -c:a:1 aac -map 0:s:0 -c:s:0 webvtt")
+	})
+
+	t.Run("Should only set -hls_list_size for HLS outputs", func(t *testing.T) {
+		file := &File{}
+		file.SetOutputPath("out.mp4")
+		require.Nil(t, file.ObtainHlsListSize())
+		file.SetOutputFormat("hls")
+		require.Equal(t, []string{"-hls_list_size", "0"}, file.ObtainHlsListSize())
+	})
+}
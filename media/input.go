@@ -0,0 +1,31 @@
+package media
+
+// Input is an input added to a File after the main one, with its own input options
+type Input struct {
+	Path          string    `json:"path,omitempty"`
+	Format        string    `json:"format,omitempty"`
+	SeekTime      string    `json:"seek_time,omitempty"`
+	Duration      string    `json:"duration,omitempty"`
+	InitialOffset string    `json:"initial_offset,omitempty"`
+	RawArgs       []string  `json:"raw_args,omitempty"`
+	Metadata      *Metadata `json:"-"`
+}
+
+// ToStrCommand returns the input options followed by -i
+func (in *Input) ToStrCommand() []string {
+	var command []string
+	if in.InitialOffset != "" {
+		command = append(command, "-itsoffset", in.InitialOffset)
+	}
+	if in.SeekTime != "" {
+		command = append(command, "-ss", in.SeekTime)
+	}
+	if in.Duration != "" {
+		command = append(command, "-t", in.Duration)
+	}
+	if in.Format != "" {
+		command = append(command, "-f", in.Format)
+	}
+	command = append(command, in.RawArgs...)
+	return append(command, "-i", in.Path)
+}
@@ -0,0 +1,154 @@
+package media
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rendition is one quality level of an adaptive bitrate ladder
+type Rendition struct {
+	Name           string `json:"name,omitempty"`  // variant name, the rendition index when empty
+	Width          int    `json:"width,omitempty"` // derived from the source aspect ratio when zero
+	Height         int    `json:"height,omitempty"`
+	VideoCodec     string `json:"video_codec,omitempty"` // libx264 when empty
+	VideoProfile   string `json:"video_profile,omitempty"`
+	VideoLevel     string `json:"video_level,omitempty"`     // e.g. "4.0", derived from the height when empty
+	VideoBitRate   int    `json:"video_bit_rate,omitempty"`  // bits per second
+	MaxRate        int    `json:"max_rate,omitempty"`        // bits per second, used as peak bandwidth when set
+	BufSize        int    `json:"buf_size,omitempty"`        // bits
+	AudioCodec     string `json:"audio_codec,omitempty"`     // aac when empty
+	AudioBitRate   int    `json:"audio_bit_rate,omitempty"`  // bits per second of the muxed audio, no audio when zero
+	CodecsOverride string `json:"codecs_override,omitempty"` // RFC 6381 CODECS attribute, computed when empty
+}
+
+// resolve fills the size of the rendition from the first video stream of metadata
+func (r Rendition) resolve(metadata *Metadata) Rendition {
+	if r.VideoCodec == "" {
+		r.VideoCodec = "libx264"
+	}
+	if r.AudioCodec == "" {
+		r.AudioCodec = "aac"
+	}
+	if metadata == nil || (r.Width > 0) == (r.Height > 0) {
+		return r
+	}
+	video := metadata.FirstVideoStream()
+	if video == nil || video.Width == 0 || video.Height == 0 {
+		return r
+	}
+	// Keep the derived side even for chroma subsampled formats
+	if r.Width == 0 {
+		r.Width = (r.Height*video.Width/video.Height + 1) &^ 1
+	} else {
+		r.Height = (r.Width*video.Height/video.Width + 1) &^ 1
+	}
+	return r
+}
+
+func (r Rendition) scaleFilter() string {
+	width, height := r.Width, r.Height
+	if width == 0 {
+		width = -2
+	}
+	if height == 0 {
+		height = -2
+	}
+	return fmt.Sprintf("scale=%d:%d", width, height)
+}
+
+// Bandwidth returns the peak and average bits per second of the rendition
+func (r Rendition) Bandwidth(audioBitRate int) (peak, average int) {
+	average = r.VideoBitRate + audioBitRate
+	peak = average
+	if r.MaxRate > 0 {
+		peak = r.MaxRate + audioBitRate
+	}
+	return peak, average
+}
+
+// Codecs returns the RFC 6381 codec strings of the rendition
+func (r Rendition) Codecs(audioCodec string) string {
+	if r.CodecsOverride != "" {
+		return r.CodecsOverride
+	}
+	codecs := []string{VideoCodecString(r.VideoCodec, r.VideoProfile, r.level())}
+	if audioCodec != "" {
+		codecs = append(codecs, AudioCodecString(audioCodec, ""))
+	}
+	return strings.Join(codecs, ",")
+}
+
+// levelArgs returns the -level option of the nth video stream, so the encoder
+// uses the level advertised by Codecs. Only H.264 encoders take the dotted form.
+func (r Rendition) levelArgs(index string) []string {
+	switch r.VideoCodec {
+	case "libx264", "h264_nvenc":
+		return []string{"-level:v:" + index, r.level()}
+	}
+	return nil
+}
+
+func (r Rendition) level() string {
+	if r.VideoLevel != "" {
+		return r.VideoLevel
+	}
+	switch {
+	case r.Height <= 480:
+		return "3.0"
+	case r.Height <= 720:
+		return "3.1"
+	case r.Height <= 1080:
+		return "4.0"
+	default:
+		return "5.1"
+	}
+}
+
+// VideoCodecString returns the RFC 6381 string of an ffmpeg video encoder or codec
+func VideoCodecString(codec, profile, level string) string {
+	var major, minor int
+	fmt.Sscanf(level, "%d.%d", &major, &minor)
+	levelID := major*10 + minor
+
+	switch codec {
+	case "libx265", "hevc", "hevc_nvenc", "hevc_qsv", "hevc_videotoolbox":
+		if profile == "main10" {
+			return fmt.Sprintf("hvc1.2.4.L%d.B0", levelID*3)
+		}
+		return fmt.Sprintf("hvc1.1.6.L%d.B0", levelID*3)
+	case "libvpx-vp9", "vp9":
+		return fmt.Sprintf("vp09.00.%02d.08", levelID)
+	case "libaom-av1", "libsvtav1", "av1":
+		return "av01.0.08M.08"
+	default:
+		profileID := map[string]string{"baseline": "42E0", "main": "4D40", "high": "6400"}[strings.ToLower(profile)]
+		if profileID == "" {
+			profileID = "6400"
+		}
+		return fmt.Sprintf("avc1.%s%02X", profileID, levelID)
+	}
+}
+
+// AudioCodecString returns the RFC 6381 string of an ffmpeg audio encoder or codec
+func AudioCodecString(codec, profile string) string {
+	switch codec {
+	case "libmp3lame", "mp3":
+		return "mp4a.40.34"
+	case "ac3":
+		return "ac-3"
+	case "eac3":
+		return "ec-3"
+	case "libopus", "opus":
+		return "opus"
+	case "flac":
+		return "fLaC"
+	default:
+		switch profile {
+		case "aac_he":
+			return "mp4a.40.5"
+		case "aac_he_v2":
+			return "mp4a.40.29"
+		}
+		return "mp4a.40.2"
+	}
+}
@@ -0,0 +1,114 @@
+package media
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// StreamType is the stream type letter used by ffmpeg stream specifiers
+type StreamType string
+
+const (
+	StreamTypeVideo         StreamType = "v"
+	StreamTypeVideoNoImages StreamType = "V" // video streams which are not attached pictures
+	StreamTypeAudio         StreamType = "a"
+	StreamTypeSubtitle      StreamType = "s"
+	StreamTypeData          StreamType = "d"
+	StreamTypeAttachment    StreamType = "t"
+)
+
+// AnyIndex selects every stream matching the rest of a StreamSpecifier
+const AnyIndex = -1
+
+// StreamSpecifier selects input streams for a -map option
+type StreamSpecifier struct {
+	Input    int        `json:"input,omitempty"`
+	Type     StreamType `json:"type,omitempty"`
+	Index    int        `json:"index"`              // type-relative index when Type is set, absolute otherwise, or AnyIndex, the JSON default
+	Language string     `json:"language,omitempty"` // language metadata, selects every matching stream and ignores Index
+	Optional bool       `json:"optional,omitempty"` // do not fail when nothing matches
+	Negative bool       `json:"negative,omitempty"` // remove the matching streams from previous maps
+}
+
+// UnmarshalJSON decodes a specifier, selecting AnyIndex when index is omitted as
+// MapInput and MapType do
+func (s *StreamSpecifier) UnmarshalJSON(data []byte) error {
+	type specifier StreamSpecifier
+	v := specifier{Index: AnyIndex}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+	*s = StreamSpecifier(v)
+	return nil
+}
+
+// MapInput selects every stream of an input
+func MapInput(input int) StreamSpecifier {
+	return StreamSpecifier{Input: input, Index: AnyIndex}
+}
+
+// MapType selects every stream of a type in an input
+func MapType(input int, streamType StreamType) StreamSpecifier {
+	return StreamSpecifier{Input: input, Type: streamType, Index: AnyIndex}
+}
+
+// MapTypeIndex selects the nth stream of a type in an input
+func MapTypeIndex(input int, streamType StreamType, index int) StreamSpecifier {
+	return StreamSpecifier{Input: input, Type: streamType, Index: index}
+}
+
+// MapStream selects a stream returned by ffprobe for an input
+func MapStream(input int, stream Stream) StreamSpecifier {
+	return StreamSpecifier{Input: input, Index: stream.Index}
+}
+
+// orDefault returns the nth stream of streamType in input 0 when s is unset
+func (s StreamSpecifier) orDefault(streamType StreamType, n int) StreamSpecifier {
+	if s == (StreamSpecifier{}) {
+		return MapTypeIndex(0, streamType, n)
+	}
+	return s
+}
+
+// WithLanguage returns a copy selecting the streams tagged with language
+func (s StreamSpecifier) WithLanguage(language string) StreamSpecifier {
+	s.Language = language
+	return s
+}
+
+// AsOptional returns a copy which does not fail when nothing matches
+func (s StreamSpecifier) AsOptional() StreamSpecifier {
+	s.Optional = true
+	return s
+}
+
+// Negated returns a copy removing the matching streams
+func (s StreamSpecifier) Negated() StreamSpecifier {
+	s.Negative = true
+	return s
+}
+
+// String returns the value of the -map option
+func (s StreamSpecifier) String() string {
+	var spec strings.Builder
+	if s.Negative {
+		spec.WriteString("-")
+	}
+	spec.WriteString(strconv.Itoa(s.Input))
+	if s.Type != "" {
+		spec.WriteString(":" + string(s.Type))
+	}
+	if s.Language != "" {
+		spec.WriteString(":m:language:" + s.Language)
+	} else if s.Index >= 0 {
+		spec.WriteString(":" + strconv.Itoa(s.Index))
+	}
+	if s.Optional {
+		spec.WriteString("?")
+	}
+	return spec.String()
+}
@@ -0,0 +1,39 @@
+package media
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const mappingStreams = `{"streams": [
+	{"index": 0, "codec_type": "video"},
+	{"index": 1, "codec_type": "audio", "tags": {"language": "spa"}, "disposition": {"default": 1}},
+	{"index": 2, "codec_type": "audio", "tags": {"language": "eng"}},
+	{"index": 3, "codec_type": "subtitle", "tags": {"language": "eng"}},
+	{"index": 4, "codec_type": "subtitle", "tags": {"language": "spa"}, "disposition": {"default": 1}}
+]}`
+
+func TestStreamSpecifier(t *testing.T) {
+	t.Run("Should build -map values", func(t *testing.T) {
+		require.Equal(t, "0", MapInput(0).String())
+		require.Equal(t, "1:a", MapType(1, StreamTypeAudio).String())
+		require.Equal(t, "0:v:0", MapTypeIndex(0, StreamTypeVideo, 0).String())
+		require.Equal(t, "0:s:m:language:eng?", MapType(0, StreamTypeSubtitle).WithLanguage("eng").AsOptional().String())
+		require.Equal(t, "-0:d", MapType(0, StreamTypeData).Negated().String())
+	})
+
+	t.Run("Should map streams selected from the metadata", func(t *testing.T) {
+		var metadata Metadata
+		require.NoError(t, json.Unmarshal([]byte(mappingStreams), &metadata))
+
+		file := &File{}
+		file.AddMap(
+			MapStream(0, *metadata.FirstStreamByLanguage(CodecTypeAudio, "eng")),
+			MapStream(0, *metadata.DefaultStream(CodecTypeSubtitle)),
+		)
+		require.Equal(t, []string{"-map", "0:2", "-map", "0:4"}, file.ObtainMaps())
+		require.Nil(t, metadata.FirstStreamByLanguage(CodecTypeAudio, "fra"))
+	})
+}
@@ -1,18 +1,16 @@
 package media
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"os/exec"
-	"strings"
+	"context"
 
 	"github.com/graux/goffmpeg"
 )
 
 type Metadata struct {
-	Streams []Stream `json:"streams"`
-	Format  Format   `json:"format"`
+	Streams  []Stream  `json:"streams"`
+	Format   Format    `json:"format"`
+	Chapters []Chapter `json:"chapters"` // read WithChapters
+	Programs []Program `json:"programs"` // read WithPrograms
 }
 
 func (m Metadata) VideoStreams() []Stream {
@@ -23,6 +21,60 @@ func (m Metadata) AudioStreams() []Stream {
 	return m.filterStreams(CodecTypeAudio)
 }
 
+func (m Metadata) SubtitleStreams() []Stream {
+	return m.filterStreams(CodecTypeSubtitle)
+}
+
+// FindStream returns the first stream accepted by match
+func (m Metadata) FindStream(match func(Stream) bool) *Stream {
+	for i := range m.Streams {
+		if match(m.Streams[i]) {
+			return &m.Streams[i]
+		}
+	}
+	return nil
+}
+
+// FirstStreamByLanguage returns the first stream of a type tagged with language
+func (m Metadata) FirstStreamByLanguage(codecType CodecType, language string) *Stream {
+	return m.FindStream(func(s Stream) bool {
+		return s.CodecType == codecType && s.Language() == language
+	})
+}
+
+// DefaultStream returns the stream of a type with the default disposition
+func (m Metadata) DefaultStream(codecType CodecType) *Stream {
+	return m.FindStream(func(s Stream) bool {
+		return s.CodecType == codecType && s.Disposition.Default == 1
+	})
+}
+
+// CodecParametersMatch reports whether other has the same streams, in the same
+// order, with the same codec parameters, so both can be joined without re-encoding
+func (m Metadata) CodecParametersMatch(other Metadata) bool {
+	if len(m.Streams) != len(other.Streams) {
+		return false
+	}
+	for i, stream := range m.Streams {
+		o := other.Streams[i]
+		if stream.CodecType != o.CodecType || stream.CodecName != o.CodecName || stream.Profile != o.Profile {
+			return false
+		}
+		switch stream.CodecType {
+		case CodecTypeVideo:
+			if stream.Width != o.Width || stream.Height != o.Height || stream.PixFmt != o.PixFmt ||
+				stream.SampleAspectRatio != o.SampleAspectRatio || stream.TimeBase != o.TimeBase {
+				return false
+			}
+		case CodecTypeAudio:
+			if stream.SampleRate != o.SampleRate || stream.Channels != o.Channels || stream.SampleFmt != o.SampleFmt {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (m Metadata) filterStreams(codecType CodecType) []Stream {
 	streams := make([]Stream, 0)
 	for _, stream := range m.Streams {
@@ -41,6 +93,10 @@ func (m Metadata) FirstAudioStream() *Stream {
 	return m.firstStream(CodecTypeAudio)
 }
 
+func (m Metadata) FirstSubtitleStream() *Stream {
+	return m.firstStream(CodecTypeSubtitle)
+}
+
 func (m Metadata) firstStream(codecType CodecType) *Stream {
 	streams := m.filterStreams(codecType)
 	if len(streams) == 0 {
@@ -57,26 +113,15 @@ func (m Metadata) IsVideoRotated() *bool {
 	return videoStream.IsRotated()
 }
 
-func NewMetadata(cfg goffmpeg.Configuration, inputPath string, whiteListProtocols ...string) (*Metadata, error) {
-	var outb, errb bytes.Buffer
-	metadata := new(Metadata)
-	command := []string{"-i", inputPath, "-print_format", "json", "-show_format", "-show_streams", "-show_error"}
-
-	if len(whiteListProtocols) > 0 {
-		command = append([]string{"-protocol_whitelist", strings.Join(whiteListProtocols, ",")}, command...)
-	}
-
-	cmd := exec.Command(cfg.FFprobeBinPath(), command...)
-	cmd.Stdout = &outb
-	cmd.Stderr = &errb
-
-	err := cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf("error executing (%s) | error: %s | message: %s %s", command, err, outb.String(), errb.String())
-	}
+// probeError is the object written by ffprobe's -show_error
+type probeError struct {
+	Error *struct {
+		Code   int    `json:"code"`
+		String string `json:"string"`
+	} `json:"error"`
+}
 
-	if err = json.Unmarshal(outb.Bytes(), metadata); err != nil {
-		return nil, err
-	}
-	return metadata, nil
+// NewMetadata reads the format and streams of inputPath
+func NewMetadata(cfg goffmpeg.Configuration, inputPath string, whiteListProtocols ...string) (*Metadata, error) {
+	return NewProber(cfg).Probe(context.Background(), inputPath, WithProtocolWhitelist(whiteListProtocols...))
 }
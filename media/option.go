@@ -0,0 +1,269 @@
+package media
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Placement is the part of the ffmpeg command an option belongs to
+type Placement int
+
+const (
+	PlacementGlobal Placement = iota // before the inputs
+	PlacementInput                   // with the main input, before its -i
+	PlacementOutput                  // with each output, before its destination
+)
+
+func (p Placement) String() string {
+	switch p {
+	case PlacementGlobal:
+		return "global"
+	case PlacementInput:
+		return "input"
+	case PlacementOutput:
+		return "output"
+	}
+	return fmt.Sprintf("Placement(%d)", int(p))
+}
+
+// Option builds the arguments of one File setting, nil when it is unset
+type Option struct {
+	Name      string
+	Placement Placement
+	Args      func(*File) []string
+}
+
+// ValueOption is an option passing the value set with File.SetOptionValue(name)
+// to flag, e.g. ValueOption("max_muxing_queue_size", "-max_muxing_queue_size", PlacementOutput)
+func ValueOption(name, flag string, placement Placement) Option {
+	return Option{Name: name, Placement: placement, Args: func(m *File) []string {
+		if v := m.OptionValue(name); v != "" {
+			return []string{flag, v}
+		}
+		return nil
+	}}
+}
+
+// OptionRegistry is the ordered list of options a File is turned into a command
+// with. It is safe for concurrent use.
+type OptionRegistry struct {
+	mu      sync.RWMutex
+	options []Option
+}
+
+// NewOptionRegistry returns a registry of options, in the order given
+func NewOptionRegistry(options ...Option) (*OptionRegistry, error) {
+	r := new(OptionRegistry)
+	for _, option := range options {
+		if err := r.insert(len(r.options), option); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// DefaultOptions returns the built-in options of File, in command order
+func DefaultOptions() []Option {
+	return []Option{
+		{Name: "HideBanner", Placement: PlacementGlobal, Args: (*File).ObtainHideBanner},
+		{Name: "SeekTimeInput", Placement: PlacementInput, Args: (*File).ObtainSeekTimeInput},
+		{Name: "SeekUsingTsInput", Placement: PlacementInput, Args: (*File).ObtainSeekUsingTsInput},
+		{Name: "NativeFramerateInput", Placement: PlacementInput, Args: (*File).ObtainNativeFramerateInput},
+		{Name: "DurationInput", Placement: PlacementInput, Args: (*File).ObtainDurationInput},
+		{Name: "RtmpLive", Placement: PlacementInput, Args: (*File).ObtainRtmpLive},
+		{Name: "InputInitialOffset", Placement: PlacementInput, Args: (*File).ObtainInputInitialOffset},
+		{Name: "HardwareAcceleration", Placement: PlacementInput, Args: (*File).ObtainHardwareAcceleration},
+		{Name: "RawInputArgs", Placement: PlacementInput, Args: (*File).ObtainRawInputArgs},
+		{Name: "InputPath", Placement: PlacementInput, Args: (*File).ObtainInputPath},
+		{Name: "InputPipe", Placement: PlacementInput, Args: (*File).ObtainInputPipe},
+		{Name: "Inputs", Placement: PlacementInput, Args: (*File).ObtainInputs},
+		{Name: "FilterGraph", Placement: PlacementOutput, Args: (*File).ObtainFilterGraph},
+		{Name: "Maps", Placement: PlacementOutput, Args: (*File).ObtainMaps},
+		{Name: "SubtitleTracks", Placement: PlacementOutput, Args: (*File).ObtainSubtitleTracks},
+		{Name: "Aspect", Placement: PlacementOutput, Args: (*File).ObtainAspect},
+		{Name: "Resolution", Placement: PlacementOutput, Args: (*File).ObtainResolution},
+		{Name: "FrameRate", Placement: PlacementOutput, Args: (*File).ObtainFrameRate},
+		{Name: "FpsMode", Placement: PlacementOutput, Args: (*File).ObtainFpsMode},
+		{Name: "AudioRate", Placement: PlacementOutput, Args: (*File).ObtainAudioRate},
+		{Name: "VideoCodec", Placement: PlacementOutput, Args: (*File).ObtainVideoCodec},
+		{Name: "Vframes", Placement: PlacementOutput, Args: (*File).ObtainVframes},
+		{Name: "VideoBitRate", Placement: PlacementOutput, Args: (*File).ObtainVideoBitRate},
+		{Name: "VideoBitRateTolerance", Placement: PlacementOutput, Args: (*File).ObtainVideoBitRateTolerance},
+		{Name: "VideoMaxBitRate", Placement: PlacementOutput, Args: (*File).ObtainVideoMaxBitRate},
+		{Name: "VideoMinBitRate", Placement: PlacementOutput, Args: (*File).ObtainVideoMinBitRate},
+		{Name: "VideoProfile", Placement: PlacementOutput, Args: (*File).ObtainVideoProfile},
+		{Name: "SkipVideo", Placement: PlacementOutput, Args: (*File).ObtainSkipVideo},
+		{Name: "AudioCodec", Placement: PlacementOutput, Args: (*File).ObtainAudioCodec},
+		{Name: "AudioBitRate", Placement: PlacementOutput, Args: (*File).ObtainAudioBitRate},
+		{Name: "AudioChannels", Placement: PlacementOutput, Args: (*File).ObtainAudioChannels},
+		{Name: "AudioProfile", Placement: PlacementOutput, Args: (*File).ObtainAudioProfile},
+		{Name: "SkipAudio", Placement: PlacementOutput, Args: (*File).ObtainSkipAudio},
+		{Name: "SubtitleCodec", Placement: PlacementOutput, Args: (*File).ObtainSubtitleCodec},
+		{Name: "CRF", Placement: PlacementOutput, Args: (*File).ObtainCRF},
+		{Name: "QScale", Placement: PlacementOutput, Args: (*File).ObtainQScale},
+		{Name: "Strict", Placement: PlacementOutput, Args: (*File).ObtainStrict},
+		{Name: "SingleFile", Placement: PlacementOutput, Args: (*File).ObtainSingleFile},
+		{Name: "BufferSize", Placement: PlacementOutput, Args: (*File).ObtainBufferSize},
+		{Name: "MuxDelay", Placement: PlacementOutput, Args: (*File).ObtainMuxDelay},
+		{Name: "Threads", Placement: PlacementOutput, Args: (*File).ObtainThreads},
+		{Name: "KeyframeInterval", Placement: PlacementOutput, Args: (*File).ObtainKeyframeInterval},
+		{Name: "Preset", Placement: PlacementOutput, Args: (*File).ObtainPreset},
+		{Name: "PixFmt", Placement: PlacementOutput, Args: (*File).ObtainPixFmt},
+		{Name: "Tune", Placement: PlacementOutput, Args: (*File).ObtainTune},
+		{Name: "Target", Placement: PlacementOutput, Args: (*File).ObtainTarget},
+		{Name: "SeekTime", Placement: PlacementOutput, Args: (*File).ObtainSeekTime},
+		{Name: "Duration", Placement: PlacementOutput, Args: (*File).ObtainDuration},
+		{Name: "CopyTs", Placement: PlacementOutput, Args: (*File).ObtainCopyTs},
+		{Name: "StreamIds", Placement: PlacementOutput, Args: (*File).ObtainStreamIds},
+		{Name: "MovFlags", Placement: PlacementOutput, Args: (*File).ObtainMovFlags},
+		{Name: "RawOutputArgs", Placement: PlacementOutput, Args: (*File).ObtainRawOutputArgs},
+		{Name: "OutputFormat", Placement: PlacementOutput, Args: (*File).ObtainOutputFormat},
+		{Name: "HlsListSize", Placement: PlacementOutput, Args: (*File).ObtainHlsListSize},
+		{Name: "HlsSegmentDuration", Placement: PlacementOutput, Args: (*File).ObtainHlsSegmentDuration},
+		{Name: "HlsPlaylistType", Placement: PlacementOutput, Args: (*File).ObtainHlsPlaylistType},
+		{Name: "HlsMasterPlaylistName", Placement: PlacementOutput, Args: (*File).ObtainHlsMasterPlaylistName},
+		{Name: "HlsSegmentFilename", Placement: PlacementOutput, Args: (*File).ObtainHlsSegmentFilename},
+		{Name: "HLSLadder", Placement: PlacementOutput, Args: (*File).ObtainHLSLadder},
+		{Name: "DASHLadder", Placement: PlacementOutput, Args: (*File).ObtainDASHLadder},
+		{Name: "AudioFilter", Placement: PlacementOutput, Args: (*File).ObtainAudioFilter},
+		{Name: "VideoFilter", Placement: PlacementOutput, Args: (*File).ObtainVideoFilter},
+		{Name: "HttpMethod", Placement: PlacementOutput, Args: (*File).ObtainHttpMethod},
+		{Name: "HttpKeepAlive", Placement: PlacementOutput, Args: (*File).ObtainHttpKeepAlive},
+		{Name: "CompressionLevel", Placement: PlacementOutput, Args: (*File).ObtainCompressionLevel},
+		{Name: "MapMetadata", Placement: PlacementOutput, Args: (*File).ObtainMapMetadata},
+		{Name: "Tags", Placement: PlacementOutput, Args: (*File).ObtainTags},
+		{Name: "EncryptionKey", Placement: PlacementOutput, Args: (*File).ObtainEncryptionKey},
+		{Name: "HLSEncryption", Placement: PlacementOutput, Args: (*File).ObtainHLSEncryption},
+		{Name: "Bframe", Placement: PlacementOutput, Args: (*File).ObtainBframe},
+		{Name: "OutputPipe", Placement: PlacementOutput, Args: (*File).ObtainOutputPipe},
+		{Name: "OutputPath", Placement: PlacementOutput, Args: (*File).ObtainOutputPath},
+	}
+}
+
+// DefaultOptionRegistry is used by files without their own registry
+var DefaultOptionRegistry = mustOptionRegistry(DefaultOptions()...)
+
+func mustOptionRegistry(options ...Option) *OptionRegistry {
+	r, err := NewOptionRegistry(options...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Options Get the registered options, in command order
+func (r *OptionRegistry) Options() []Option {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Option(nil), r.options...)
+}
+
+// Clone returns a registry with the same options, to customize without
+// affecting the files using r
+func (r *OptionRegistry) Clone() *OptionRegistry {
+	return &OptionRegistry{options: r.Options()}
+}
+
+// Register adds option at the end of its placement: after the global options,
+// right before the main input path for input options, and right before the
+// destination for output options.
+func (r *OptionRegistry) Register(option Option) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := len(r.options)
+	anchor := map[Placement]string{PlacementInput: "InputPath", PlacementOutput: "OutputPipe"}[option.Placement]
+	if i := r.index(anchor); anchor != "" && i >= 0 {
+		index = i
+	}
+	return r.insert(index, option)
+}
+
+// RegisterBefore adds option right before the option named anchor
+func (r *OptionRegistry) RegisterBefore(anchor string, option Option) error {
+	return r.registerAt(anchor, 0, option)
+}
+
+// RegisterAfter adds option right after the option named anchor
+func (r *OptionRegistry) RegisterAfter(anchor string, option Option) error {
+	return r.registerAt(anchor, 1, option)
+}
+
+func (r *OptionRegistry) registerAt(anchor string, offset int, option Option) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.index(anchor)
+	if i < 0 {
+		return fmt.Errorf("option %q: unknown anchor option %q", option.Name, anchor)
+	}
+	if r.options[i].Placement != option.Placement {
+		return fmt.Errorf("option %q: %s option cannot be placed next to %s option %q",
+			option.Name, option.Placement, r.options[i].Placement, anchor)
+	}
+	return r.insert(i+offset, option)
+}
+
+func (r *OptionRegistry) insert(index int, option Option) error {
+	switch {
+	case option.Name == "":
+		return fmt.Errorf("option name cannot be empty")
+	case option.Args == nil:
+		return fmt.Errorf("option %q: Args cannot be nil", option.Name)
+	case option.Placement < PlacementGlobal || option.Placement > PlacementOutput:
+		return fmt.Errorf("option %q: invalid placement %s", option.Name, option.Placement)
+	case r.index(option.Name) >= 0:
+		return fmt.Errorf("option %q is already registered", option.Name)
+	}
+
+	r.options = append(r.options, Option{})
+	copy(r.options[index+1:], r.options[index:])
+	r.options[index] = option
+	return nil
+}
+
+func (r *OptionRegistry) index(name string) int {
+	for i, option := range r.options {
+		if option.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// args Get the arguments of the options placed at placement
+func (r *OptionRegistry) args(m *File, placement Placement) []string {
+	var args []string
+	for _, option := range r.Options() {
+		if option.Placement == placement {
+			args = append(args, option.Args(m)...)
+		}
+	}
+	return args
+}
+
+// SetOptionRegistry Set the options the file is turned into a command with. The
+// outputs added with AddOutput use it too, unless they have their own.
+func (m *File) SetOptionRegistry(r *OptionRegistry) {
+	m.optionRegistry = r
+}
+
+// OptionRegistry Get the options of the file, DefaultOptionRegistry when unset
+func (m *File) OptionRegistry() *OptionRegistry {
+	if m.optionRegistry == nil {
+		return DefaultOptionRegistry
+	}
+	return m.optionRegistry
+}
+
+// SetOptionValue Set the value of a custom option, see ValueOption
+func (m *File) SetOptionValue(name, value string) {
+	if m.optionValues == nil {
+		m.optionValues = make(map[string]string)
+	}
+	m.optionValues[name] = value
+}
+
+func (m *File) OptionValue(name string) string {
+	return m.optionValues[name]
+}
@@ -0,0 +1,110 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/graux/goffmpeg"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionRegistry(t *testing.T) {
+	t.Run("Should build the same command for maps on every run", func(t *testing.T) {
+		file := &File{}
+		file.SetTags(map[string]string{"title": "T", "artist": "A", "album": "B", "year": "2024"})
+		file.SetStreamIds(map[int]string{3: "d", 1: "b", 0: "a", 2: "c"})
+		file.SetOutputPath("out.mp4")
+
+		expected := []string{
+			"-streamid", "0:a", "-streamid", "1:b", "-streamid", "2:c", "-streamid", "3:d",
+			"-metadata", "album=B", "-metadata", "artist=A", "-metadata", "title=T", "-metadata", "year=2024",
+			"out.mp4",
+		}
+		for i := 0; i < 20; i++ {
+			require.Equal(t, expected, file.ToStrCommand())
+		}
+	})
+
+	t.Run("Should place global options first", func(t *testing.T) {
+		file := &File{}
+		file.SetSeekTimeInput("10")
+		file.SetInputPath("in.mp4")
+		file.SetHideBanner(true)
+		file.SetOutputPath("out.mp4")
+		require.Equal(t, []string{"-hide_banner", "-ss", "10", "-i", "in.mp4", "out.mp4"}, file.ToStrCommand())
+	})
+
+	t.Run("Should slot custom options in at their placement", func(t *testing.T) {
+		registry := DefaultOptionRegistry.Clone()
+		require.NoError(t, registry.Register(ValueOption("max_muxing_queue_size", "-max_muxing_queue_size", PlacementOutput)))
+		require.NoError(t, registry.Register(ValueOption("probesize", "-probesize", PlacementInput)))
+		require.NoError(t, registry.Register(Option{Name: "stats_period", Placement: PlacementGlobal, Args: func(*File) []string {
+			return []string{"-stats_period", "1"}
+		}}))
+		require.NoError(t, registry.RegisterBefore("VideoCodec", ValueOption("fps_mode", "-fps_mode", PlacementOutput)))
+
+		file := &File{}
+		file.SetOptionRegistry(registry)
+		file.SetInputPath("in.mp4")
+		file.SetVideoCodec("libx264")
+		file.SetOutputPath("out.mp4")
+		file.SetOptionValue("max_muxing_queue_size", "1024")
+		file.SetOptionValue("probesize", "5M")
+		file.SetOptionValue("fps_mode", "cfr")
+
+		require.Equal(t, []string{
+			"-stats_period", "1", "-probesize", "5M", "-i", "in.mp4",
+			"-fps_mode", "cfr", "-c:v", "libx264", "-max_muxing_queue_size", "1024", "out.mp4",
+		}, file.ToStrCommand())
+		require.NotContains(t, (&File{}).ToStrCommand(), "-stats_period")
+	})
+
+	t.Run("Should apply the custom options of the file to its outputs", func(t *testing.T) {
+		registry := DefaultOptionRegistry.Clone()
+		require.NoError(t, registry.Register(ValueOption("max_muxing_queue_size", "-max_muxing_queue_size", PlacementOutput)))
+
+		file := &File{}
+		file.SetOptionRegistry(registry)
+		file.SetInputPath("in.mp4")
+		file.SetOutputPath("out.mp4")
+		output := &File{}
+		output.SetOptionValue("max_muxing_queue_size", "1024")
+		output.SetOutputPath("audio.m4a")
+		file.AddOutput(output)
+
+		require.Equal(t, []string{"-i", "in.mp4", "out.mp4", "-max_muxing_queue_size", "1024", "audio.m4a"}, file.ToStrCommand())
+	})
+
+	t.Run("Should reject invalid registrations", func(t *testing.T) {
+		registry := DefaultOptionRegistry.Clone()
+		require.ErrorContains(t, registry.Register(ValueOption("VideoCodec", "-c:v", PlacementOutput)), "already registered")
+		require.ErrorContains(t, registry.RegisterAfter("Codec", ValueOption("x", "-x", PlacementOutput)), "unknown anchor")
+		require.ErrorContains(t, registry.RegisterAfter("InputPath", ValueOption("y", "-y", PlacementOutput)), "cannot be placed")
+		require.Error(t, registry.Register(Option{Name: "z", Placement: PlacementOutput}))
+	})
+}
+
+func TestFpsMode(t *testing.T) {
+	t.Run("Should use -vsync when the ffmpeg version is unknown or older than 5.1", func(t *testing.T) {
+		file := &File{}
+		file.SetFpsMode("cfr")
+		require.Equal(t, []string{"-vsync", "cfr"}, file.ObtainFpsMode())
+
+		file.SetFFmpegVersion(goffmpeg.Version{Major: 4, Minor: 4, Patch: 2})
+		require.Equal(t, []string{"-vsync", "cfr"}, file.ObtainFpsMode())
+	})
+
+	t.Run("Should use -fps_mode from ffmpeg 5.1 on every output", func(t *testing.T) {
+		file := &File{}
+		file.SetFpsMode("passthrough")
+		file.SetOutputPath("out.mp4")
+
+		output := &File{}
+		output.SetFpsMode("vfr")
+		output.SetOutputPath("other.mp4")
+		file.AddOutput(output)
+
+		file.SetFFmpegVersion(goffmpeg.Version{Major: 6})
+		require.Equal(t, []string{"-fps_mode", "passthrough", "out.mp4", "-fps_mode", "vfr", "other.mp4"}, file.ToStrCommand())
+	})
+}
@@ -0,0 +1,420 @@
+package media
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileOptions are the serializable settings of a File. They marshal to a JSON
+// object whose keys are the snake_case names of the File setters, e.g.
+// {"video_codec": "libx264", "crf": 23, "output_path": "out.mp4"}, with unset
+// settings omitted. Pipes, probed metadata and filter graphs are not part of it,
+// and durations of nested settings are in nanoseconds.
+type FileOptions struct {
+	Aspect                string            `json:"aspect,omitempty"`
+	Resolution            string            `json:"resolution,omitempty"`
+	VideoBitRate          string            `json:"video_bit_rate,omitempty"`
+	VideoBitRateTolerance int               `json:"video_bit_rate_tolerance,omitempty"`
+	VideoMaxBitRate       int               `json:"video_max_bit_rate,omitempty"`
+	VideoMinBitRate       int               `json:"video_min_bit_rate,omitempty"`
+	VideoCodec            string            `json:"video_codec,omitempty"`
+	Vframes               int               `json:"vframes,omitempty"`
+	FrameRate             int               `json:"frame_rate,omitempty"`
+	FpsMode               string            `json:"fps_mode,omitempty"`
+	AudioRate             int               `json:"audio_rate,omitempty"`
+	MaxKeyframe           int               `json:"max_keyframe,omitempty"`
+	MinKeyframe           int               `json:"min_keyframe,omitempty"`
+	KeyframeInterval      int               `json:"keyframe_interval,omitempty"`
+	AudioCodec            string            `json:"audio_codec,omitempty"`
+	SubtitleCodec         string            `json:"subtitle_codec,omitempty"`
+	AudioBitRate          string            `json:"audio_bit_rate,omitempty"`
+	AudioChannels         int               `json:"audio_channels,omitempty"`
+	AudioVariableBitrate  bool              `json:"audio_variable_bitrate,omitempty"`
+	BufferSize            int               `json:"buffer_size,omitempty"`
+	Threadset             bool              `json:"threadset,omitempty"`
+	Threads               int               `json:"threads,omitempty"`
+	Preset                string            `json:"preset,omitempty"`
+	Tune                  string            `json:"tune,omitempty"`
+	AudioProfile          string            `json:"audio_profile,omitempty"`
+	VideoProfile          string            `json:"video_profile,omitempty"`
+	Target                string            `json:"target,omitempty"`
+	Duration              string            `json:"duration,omitempty"`
+	DurationInput         string            `json:"duration_input,omitempty"`
+	SeekTime              string            `json:"seek_time,omitempty"`
+	QScale                uint32            `json:"qscale,omitempty"`
+	CRF                   uint32            `json:"crf,omitempty"`
+	Strict                int               `json:"strict,omitempty"`
+	SingleFile            int               `json:"single_file,omitempty"`
+	MuxDelay              string            `json:"mux_delay,omitempty"`
+	SeekUsingTsInput      bool              `json:"seek_using_ts_input,omitempty"`
+	SeekTimeInput         string            `json:"seek_time_input,omitempty"`
+	InputPath             string            `json:"input_path,omitempty"`
+	MovFlags              string            `json:"mov_flags,omitempty"`
+	HideBanner            bool              `json:"hide_banner,omitempty"`
+	OutputPath            string            `json:"output_path,omitempty"`
+	OutputFormat          string            `json:"output_format,omitempty"`
+	CopyTs                bool              `json:"copy_ts,omitempty"`
+	NativeFramerateInput  bool              `json:"native_framerate_input,omitempty"`
+	InputInitialOffset    string            `json:"input_initial_offset,omitempty"`
+	RtmpLive              string            `json:"rtmp_live,omitempty"`
+	HlsPlaylistType       string            `json:"hls_playlist_type,omitempty"`
+	HlsListSize           int               `json:"hls_list_size,omitempty"`
+	HlsSegmentDuration    int               `json:"hls_segment_duration,omitempty"`
+	HlsMasterPlaylistName string            `json:"hls_master_playlist_name,omitempty"`
+	HlsSegmentFilename    string            `json:"hls_segment_filename,omitempty"`
+	HLSLadder             *HLSLadder        `json:"hls_ladder,omitempty"`
+	DASHLadder            *DASHLadder       `json:"dash_ladder,omitempty"`
+	HttpMethod            string            `json:"http_method,omitempty"`
+	HttpKeepAlive         bool              `json:"http_keep_alive,omitempty"`
+	HardwareAcceleration  string            `json:"hwaccel,omitempty"`
+	StreamIds             map[int]string    `json:"stream_ids,omitempty"`
+	Maps                  []StreamSpecifier `json:"maps,omitempty"`
+	SubtitleTracks        []SubtitleTrack   `json:"subtitle_tracks,omitempty"`
+	BurnSubtitles         *BurnSubtitles    `json:"burn_subtitles,omitempty"`
+	VideoFilter           string            `json:"video_filter,omitempty"`
+	AudioFilter           string            `json:"audio_filter,omitempty"`
+	SkipVideo             bool              `json:"skip_video,omitempty"`
+	SkipAudio             bool              `json:"skip_audio,omitempty"`
+	CompressionLevel      int               `json:"compression_level,omitempty"`
+	MapMetadata           string            `json:"map_metadata,omitempty"`
+	Tags                  map[string]string `json:"tags,omitempty"`
+	EncryptionKey         string            `json:"encryption_key,omitempty"`
+	HLSEncryption         *HLSEncryption    `json:"hls_encryption,omitempty"`
+	Bframe                int               `json:"bframe,omitempty"`
+	PixFmt                string            `json:"pix_fmt,omitempty"`
+	RawInputArgs          []string          `json:"raw_input_args,omitempty"`
+	RawOutputArgs         []string          `json:"raw_output_args,omitempty"`
+	OptionValues          map[string]string `json:"option_values,omitempty"`
+	Inputs                []Input           `json:"inputs,omitempty"`
+	Outputs               []FileOptions     `json:"outputs,omitempty"`
+}
+
+// Options Get the serializable settings of the file
+func (m *File) Options() FileOptions {
+	o := FileOptions{
+		Aspect:                m.aspect,
+		Resolution:            m.resolution,
+		VideoBitRate:          m.videoBitRate,
+		VideoBitRateTolerance: m.videoBitRateTolerance,
+		VideoMaxBitRate:       m.videoMaxBitRate,
+		VideoMinBitRate:       m.videoMinBitrate,
+		VideoCodec:            m.videoCodec,
+		Vframes:               m.vframes,
+		FrameRate:             m.frameRate,
+		FpsMode:               m.fpsMode,
+		AudioRate:             m.audioRate,
+		MaxKeyframe:           m.maxKeyframe,
+		MinKeyframe:           m.minKeyframe,
+		KeyframeInterval:      m.keyframeInterval,
+		AudioCodec:            m.audioCodec,
+		SubtitleCodec:         m.subtitleCodec,
+		AudioBitRate:          m.audioBitrate,
+		AudioChannels:         m.audioChannels,
+		AudioVariableBitrate:  m.audioVariableBitrate,
+		BufferSize:            m.bufferSize,
+		Threadset:             m.threadset,
+		Threads:               m.threads,
+		Preset:                m.preset,
+		Tune:                  m.tune,
+		AudioProfile:          m.audioProfile,
+		VideoProfile:          m.videoProfile,
+		Target:                m.target,
+		Duration:              m.duration,
+		DurationInput:         m.durationInput,
+		SeekTime:              m.seekTime,
+		QScale:                m.qscale,
+		CRF:                   m.crf,
+		Strict:                m.strict,
+		SingleFile:            m.singleFile,
+		MuxDelay:              m.muxDelay,
+		SeekUsingTsInput:      m.seekUsingTsInput,
+		SeekTimeInput:         m.seekTimeInput,
+		InputPath:             m.inputPath,
+		MovFlags:              m.movFlags,
+		HideBanner:            m.hideBanner,
+		OutputPath:            m.outputPath,
+		OutputFormat:          m.outputFormat,
+		CopyTs:                m.copyTs,
+		NativeFramerateInput:  m.nativeFramerateInput,
+		InputInitialOffset:    m.inputInitialOffset,
+		RtmpLive:              m.rtmpLive,
+		HlsPlaylistType:       m.hlsPlaylistType,
+		HlsListSize:           m.hlsListSize,
+		HlsSegmentDuration:    m.hlsSegmentDuration,
+		HlsMasterPlaylistName: m.hlsMasterPlaylistName,
+		HlsSegmentFilename:    m.hlsSegmentFilename,
+		HLSLadder:             m.hlsLadder,
+		DASHLadder:            m.dashLadder,
+		HttpMethod:            m.httpMethod,
+		HttpKeepAlive:         m.httpKeepAlive,
+		HardwareAcceleration:  m.hwaccel,
+		StreamIds:             m.streamIds,
+		Maps:                  m.maps,
+		SubtitleTracks:        m.subtitleTracks,
+		BurnSubtitles:         m.burnSubtitles,
+		VideoFilter:           m.videoFilter,
+		AudioFilter:           m.audioFilter,
+		SkipVideo:             m.skipVideo,
+		SkipAudio:             m.skipAudio,
+		CompressionLevel:      m.compressionLevel,
+		MapMetadata:           m.mapMetadata,
+		Tags:                  m.tags,
+		EncryptionKey:         m.encryptionKey,
+		HLSEncryption:         m.hlsEncryption,
+		Bframe:                m.bFrame,
+		PixFmt:                m.pixFmt,
+		RawInputArgs:          m.rawInputArgs,
+		RawOutputArgs:         m.rawOutputArgs,
+		OptionValues:          m.optionValues,
+	}
+	for _, input := range m.inputs {
+		o.Inputs = append(o.Inputs, *input)
+	}
+	for _, output := range m.outputs {
+		o.Outputs = append(o.Outputs, output.Options())
+	}
+	return o
+}
+
+// ApplyOptions replaces every serializable setting of the file with o. Inputs
+// and outputs are updated by index, keeping the metadata of inputs whose path
+// is unchanged and the filter graphs and pipes of outputs. The HLS key
+// callback is kept when o.HLSEncryption has none.
+func (m *File) ApplyOptions(o FileOptions) {
+	m.aspect = o.Aspect
+	m.resolution = o.Resolution
+	m.videoBitRate = o.VideoBitRate
+	m.videoBitRateTolerance = o.VideoBitRateTolerance
+	m.videoMaxBitRate = o.VideoMaxBitRate
+	m.videoMinBitrate = o.VideoMinBitRate
+	m.videoCodec = o.VideoCodec
+	m.vframes = o.Vframes
+	m.frameRate = o.FrameRate
+	m.fpsMode = o.FpsMode
+	m.audioRate = o.AudioRate
+	m.maxKeyframe = o.MaxKeyframe
+	m.minKeyframe = o.MinKeyframe
+	m.keyframeInterval = o.KeyframeInterval
+	m.audioCodec = o.AudioCodec
+	m.subtitleCodec = o.SubtitleCodec
+	m.audioBitrate = o.AudioBitRate
+	m.audioChannels = o.AudioChannels
+	m.audioVariableBitrate = o.AudioVariableBitrate
+	m.bufferSize = o.BufferSize
+	m.threadset = o.Threadset
+	m.threads = o.Threads
+	m.preset = o.Preset
+	m.tune = o.Tune
+	m.audioProfile = o.AudioProfile
+	m.videoProfile = o.VideoProfile
+	m.target = o.Target
+	m.duration = o.Duration
+	m.durationInput = o.DurationInput
+	m.seekTime = o.SeekTime
+	m.qscale = o.QScale
+	m.crf = o.CRF
+	m.strict = o.Strict
+	m.singleFile = o.SingleFile
+	m.muxDelay = o.MuxDelay
+	m.seekUsingTsInput = o.SeekUsingTsInput
+	m.seekTimeInput = o.SeekTimeInput
+	m.inputPath = o.InputPath
+	m.movFlags = o.MovFlags
+	m.hideBanner = o.HideBanner
+	m.outputPath = o.OutputPath
+	m.outputFormat = o.OutputFormat
+	m.copyTs = o.CopyTs
+	m.nativeFramerateInput = o.NativeFramerateInput
+	m.inputInitialOffset = o.InputInitialOffset
+	m.rtmpLive = o.RtmpLive
+	m.hlsPlaylistType = o.HlsPlaylistType
+	m.hlsListSize = o.HlsListSize
+	m.hlsSegmentDuration = o.HlsSegmentDuration
+	m.hlsMasterPlaylistName = o.HlsMasterPlaylistName
+	m.hlsSegmentFilename = o.HlsSegmentFilename
+	m.hlsLadder = o.HLSLadder
+	m.dashLadder = o.DASHLadder
+	m.httpMethod = o.HttpMethod
+	m.httpKeepAlive = o.HttpKeepAlive
+	m.hwaccel = o.HardwareAcceleration
+	m.streamIds = o.StreamIds
+	m.maps = o.Maps
+	m.subtitleTracks = o.SubtitleTracks
+	m.burnSubtitles = o.BurnSubtitles
+	m.videoFilter = o.VideoFilter
+	m.audioFilter = o.AudioFilter
+	m.skipVideo = o.SkipVideo
+	m.skipAudio = o.SkipAudio
+	m.compressionLevel = o.CompressionLevel
+	m.mapMetadata = o.MapMetadata
+	m.tags = o.Tags
+	m.encryptionKey = o.EncryptionKey
+	if previous := m.hlsEncryption; previous != nil && o.HLSEncryption != nil && o.HLSEncryption.OnKey == nil {
+		o.HLSEncryption.OnKey = previous.OnKey
+	}
+	m.hlsEncryption = o.HLSEncryption
+	m.bFrame = o.Bframe
+	m.pixFmt = o.PixFmt
+	m.rawInputArgs = o.RawInputArgs
+	m.rawOutputArgs = o.RawOutputArgs
+	m.optionValues = o.OptionValues
+
+	// Inputs and outputs are updated in place, keeping the probed metadata of
+	// inputs whose path is unchanged and the state outputs cannot serialize
+	var inputs []*Input
+	for i := range o.Inputs {
+		input := o.Inputs[i]
+		if i >= len(m.inputs) {
+			inputs = append(inputs, &input)
+			continue
+		}
+		if input.Path == m.inputs[i].Path {
+			input.Metadata = m.inputs[i].Metadata
+		}
+		*m.inputs[i] = input
+		inputs = append(inputs, m.inputs[i])
+	}
+	m.inputs = inputs
+
+	var outputs []*File
+	for i, options := range o.Outputs {
+		output := new(File)
+		if i < len(m.outputs) {
+			output = m.outputs[i]
+		}
+		output.ApplyOptions(options)
+		outputs = append(outputs, output)
+	}
+	m.outputs = outputs
+}
+
+func (m *File) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Options())
+}
+
+// UnmarshalJSON replaces the settings of the file, rejecting unknown keys
+func (m *File) UnmarshalJSON(data []byte) error {
+	var o FileOptions
+	if err := decodeOptions(data, &o); err != nil {
+		return err
+	}
+	m.ApplyOptions(o)
+	return nil
+}
+
+func (m *File) MarshalYAML() (interface{}, error) {
+	return m.Options().MarshalYAML()
+}
+
+// UnmarshalYAML replaces the settings of the file, rejecting unknown keys
+func (m *File) UnmarshalYAML(value *yaml.Node) error {
+	var o FileOptions
+	if err := o.UnmarshalYAML(value); err != nil {
+		return err
+	}
+	m.ApplyOptions(o)
+	return nil
+}
+
+// MarshalYAML writes the options with the keys of their JSON form
+func (o FileOptions) MarshalYAML() (interface{}, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAMLValue(data)
+}
+
+// UnmarshalYAML reads the options with the keys of their JSON form
+func (o *FileOptions) UnmarshalYAML(value *yaml.Node) error {
+	data, err := yamlNodeToJSON(value)
+	if err != nil {
+		return err
+	}
+	return decodeOptions(data, o)
+}
+
+// decodeOptions decodes JSON data into v, rejecting unknown keys
+func decodeOptions(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid file options: %w", err)
+	}
+	return nil
+}
+
+// jsonToYAMLValue decodes JSON data keeping integers as integers, so they are
+// not written as floats in YAML
+func jsonToYAMLValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = convertNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = convertNumbers(value)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+func yamlNodeToJSON(value *yaml.Node) ([]byte, error) {
+	var v interface{}
+	if err := value.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(stringKeys(v))
+}
+
+// yamlToJSON converts a YAML document to JSON
+func yamlToJSON(r io.Reader) ([]byte, error) {
+	var v interface{}
+	if err := yaml.NewDecoder(r).Decode(&v); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return json.Marshal(stringKeys(v))
+}
+
+// stringKeys converts the maps decoded from YAML to maps JSON can encode, as
+// keys like stream_ids indexes are decoded as integers
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return converted
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+	}
+	return v
+}
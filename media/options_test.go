@@ -0,0 +1,172 @@
+package media
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func optionsFile() *File {
+	file := &File{}
+	file.SetInputPath("in.mp4")
+	file.SetVideoCodec("libx264")
+	file.SetCRF(23)
+	file.SetStreamIds(map[int]string{0: "1"})
+	file.SetTags(map[string]string{"title": "Example"})
+	file.AddMap(MapTypeIndex(0, StreamTypeVideo, 0), MapType(1, StreamTypeAudio).AsOptional())
+	file.AddInput(&Input{Path: "audio.m4a", SeekTime: "5", Metadata: &Metadata{}})
+	file.SetHLSLadder(&HLSLadder{Renditions: []HLSRendition{{Rendition: Rendition{Height: 720, VideoBitRate: 2800000}}}})
+	file.SetOutputPath("out.mp4")
+
+	audio := &File{}
+	audio.SetSkipVideo(true)
+	audio.SetOutputPath("audio.m4a")
+	file.AddOutput(audio)
+	return file
+}
+
+func TestFileOptions(t *testing.T) {
+	t.Run("Should marshal to snake_case keys without unset settings", func(t *testing.T) {
+		data, err := json.Marshal(optionsFile())
+		require.NoError(t, err)
+
+		var object map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &object))
+		require.Equal(t, "libx264", object["video_codec"])
+		require.Equal(t, float64(23), object["crf"])
+		require.Equal(t, map[string]interface{}{"0": "1"}, object["stream_ids"])
+		require.NotContains(t, object, "audio_codec")
+		require.Equal(t, []interface{}{map[string]interface{}{"path": "audio.m4a", "seek_time": "5"}}, object["inputs"])
+	})
+
+	t.Run("Should replay the same command from JSON", func(t *testing.T) {
+		file := optionsFile()
+		data, err := json.Marshal(file)
+		require.NoError(t, err)
+
+		replayed := &File{}
+		require.NoError(t, json.Unmarshal(data, replayed))
+		require.Equal(t, file.ToStrCommand(), replayed.ToStrCommand())
+	})
+
+	t.Run("Should replay the same command from YAML", func(t *testing.T) {
+		file := optionsFile()
+		data, err := yaml.Marshal(file)
+		require.NoError(t, err)
+		require.Contains(t, string(data), "video_bit_rate: 2800000")
+
+		replayed := &File{}
+		require.NoError(t, yaml.Unmarshal(data, replayed))
+		require.Equal(t, file.ToStrCommand(), replayed.ToStrCommand())
+	})
+
+	t.Run("Should replay encrypted outputs without their key callback", func(t *testing.T) {
+		file := optionsFile()
+		file.SetHLSEncryption(&HLSEncryption{KeyURI: "key-%d.bin", RotationInterval: time.Minute, OnKey: func(*HLSKey) error { return nil }})
+
+		data, err := json.Marshal(file)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "on_key")
+		replayed := &File{}
+		require.NoError(t, json.Unmarshal(data, replayed))
+		require.Equal(t, file.ToStrCommand(), replayed.ToStrCommand())
+		require.Equal(t, "key-%d.bin", replayed.HLSEncryption().KeyURI)
+
+		data, err = yaml.Marshal(file)
+		require.NoError(t, err)
+		replayed = &File{}
+		require.NoError(t, yaml.Unmarshal(data, replayed))
+		require.Equal(t, time.Minute, replayed.HLSEncryption().RotationInterval)
+	})
+
+	t.Run("Should map any index when a hand-written map omits it", func(t *testing.T) {
+		for _, data := range []string{`{"maps": [{"input": 1, "type": "a"}, {"type": "v", "index": 0}]}`, "maps:\n  - input: 1\n    type: a\n  - type: v\n    index: 0\n"} {
+			file := &File{}
+			require.NoError(t, yaml.Unmarshal([]byte(data), file))
+			require.Equal(t, []StreamSpecifier{MapType(1, StreamTypeAudio), MapTypeIndex(0, StreamTypeVideo, 0)}, file.Maps())
+
+			replayed, err := json.Marshal(file)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(replayed, file))
+			require.Equal(t, []StreamSpecifier{MapType(1, StreamTypeAudio), MapTypeIndex(0, StreamTypeVideo, 0)}, file.Maps())
+		}
+	})
+
+	t.Run("Should reject unknown keys", func(t *testing.T) {
+		err := json.Unmarshal([]byte(`{"video_codecs": "libx264"}`), &File{})
+		require.ErrorContains(t, err, "video_codecs")
+	})
+}
+
+func TestPresetRegistry(t *testing.T) {
+	registry := NewPresetRegistry()
+	require.NoError(t, registry.RegisterOptions("h264", FileOptions{VideoCodec: "libx264", Preset: "medium", CRF: 23, AudioCodec: "aac"}))
+	require.NoError(t, registry.Register("web", Preset{Extends: []string{"h264"}, Options: json.RawMessage(`{"resolution": "1280x720", "preset": "fast", "mov_flags": "+faststart"}`)}))
+	require.NoError(t, registry.Register("silent", Preset{Options: json.RawMessage(`{"audio_codec": null, "skip_audio": true}`)}))
+
+	t.Run("Should layer presets over the ones they extend", func(t *testing.T) {
+		o, err := registry.Resolve("web")
+		require.NoError(t, err)
+		require.Equal(t, FileOptions{VideoCodec: "libx264", Preset: "fast", CRF: 23, AudioCodec: "aac", Resolution: "1280x720", MovFlags: "+faststart"}, o)
+	})
+
+	t.Run("Should remove settings set to null", func(t *testing.T) {
+		o, err := registry.Resolve("web", "silent")
+		require.NoError(t, err)
+		require.Empty(t, o.AudioCodec)
+		require.True(t, o.SkipAudio)
+	})
+
+	t.Run("Should apply presets over the file settings", func(t *testing.T) {
+		file := &File{}
+		file.SetInputPath("in.mp4")
+		file.SetOutputPath("out.mp4")
+		require.NoError(t, registry.Apply(file, "web"))
+		require.Equal(t, "in.mp4", file.InputPath())
+		require.Equal(t, "fast", file.Preset())
+		require.Equal(t, "1280x720", file.Resolution())
+	})
+
+	t.Run("Should reject unknown presets, options and cycles", func(t *testing.T) {
+		_, err := registry.Resolve("mobile")
+		require.ErrorContains(t, err, `unknown preset "mobile"`)
+
+		require.Error(t, registry.Register("typo", Preset{Options: json.RawMessage(`{"codec": "libx264"}`)}))
+
+		cycles := NewPresetRegistry()
+		require.NoError(t, cycles.Register("a", Preset{Extends: []string{"b"}}))
+		require.NoError(t, cycles.Register("b", Preset{Extends: []string{"a"}}))
+		_, err = cycles.Resolve("a")
+		require.ErrorContains(t, err, "extends itself")
+	})
+
+	t.Run("Should load presets from YAML files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "presets.yaml")
+		content := strings.Join([]string{
+			"hevc:",
+			"  options:",
+			"    video_codec: libx265",
+			"    stream_ids:",
+			"      0: \"1\"",
+			"hevc-720p:",
+			"  extends: [hevc]",
+			"  options:",
+			"    resolution: 1280x720",
+		}, "\n")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		loaded := NewPresetRegistry()
+		require.NoError(t, loaded.LoadFile(path))
+		require.Equal(t, []string{"hevc", "hevc-720p"}, loaded.Names())
+
+		o, err := loaded.Resolve("hevc-720p")
+		require.NoError(t, err)
+		require.Equal(t, FileOptions{VideoCodec: "libx265", Resolution: "1280x720", StreamIds: map[int]string{0: "1"}}, o)
+	})
+}
@@ -0,0 +1,223 @@
+package media
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Preset is a named, partial set of File options, as a FileOptions JSON object.
+// Its options are layered over the presets it extends, in order: objects are
+// merged key by key, other values replace the extended ones and null removes them.
+type Preset struct {
+	Extends []string        `json:"extends,omitempty"`
+	Options json.RawMessage `json:"options"`
+}
+
+// PresetRegistry holds named presets. It is safe for concurrent use.
+type PresetRegistry struct {
+	mu      sync.RWMutex
+	presets map[string]Preset
+}
+
+func NewPresetRegistry() *PresetRegistry {
+	return &PresetRegistry{presets: make(map[string]Preset)}
+}
+
+// Register adds or replaces the preset name
+func (r *PresetRegistry) Register(name string, preset Preset) error {
+	if name == "" {
+		return fmt.Errorf("preset name cannot be empty")
+	}
+	if len(preset.Options) == 0 {
+		preset.Options = json.RawMessage("{}")
+	}
+	// Check the options are an object of known keys before accepting them
+	var options FileOptions
+	if err := decodeOptions(preset.Options, &options); err != nil {
+		return fmt.Errorf("preset %q: %w", name, err)
+	}
+	if _, err := decodeObject(preset.Options); err != nil {
+		return fmt.Errorf("preset %q: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.presets[name] = preset
+	return nil
+}
+
+// RegisterOptions adds or replaces the preset name with the settings of o.
+// Settings left unset in o keep the values of the extended presets.
+func (r *PresetRegistry) RegisterOptions(name string, o FileOptions, extends ...string) error {
+	options, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return r.Register(name, Preset{Extends: extends, Options: options})
+}
+
+// Load registers the presets of a JSON object mapping names to presets, e.g.
+// {"web": {"extends": ["h264"], "options": {"resolution": "1280x720"}}}
+func (r *PresetRegistry) Load(reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return r.load(data)
+}
+
+// LoadFile registers the presets of a JSON file, or a YAML file when its
+// extension is .yaml or .yml
+func (r *PresetRegistry) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err := yamlToJSON(f)
+		if err != nil {
+			return fmt.Errorf("invalid presets file %s: %w", path, err)
+		}
+		return r.load(data)
+	default:
+		return r.Load(f)
+	}
+}
+
+func (r *PresetRegistry) load(data []byte) error {
+	var presets map[string]Preset
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&presets); err != nil {
+		return fmt.Errorf("invalid presets: %w", err)
+	}
+
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := r.Register(name, presets[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names Get the sorted names of the registered presets
+func (r *PresetRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.presets))
+	for name := range r.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve layers the named presets, each over the previous ones
+func (r *PresetRegistry) Resolve(names ...string) (FileOptions, error) {
+	var o FileOptions
+	err := r.resolveOver(map[string]interface{}{}, names, &o)
+	return o, err
+}
+
+// Apply layers the named presets over the current settings of file
+func (r *PresetRegistry) Apply(file *File, names ...string) error {
+	current, err := json.Marshal(file.Options())
+	if err != nil {
+		return err
+	}
+	base, err := decodeObject(current)
+	if err != nil {
+		return err
+	}
+
+	var o FileOptions
+	if err := r.resolveOver(base, names, &o); err != nil {
+		return err
+	}
+	file.ApplyOptions(o)
+	return nil
+}
+
+func (r *PresetRegistry) resolveOver(base map[string]interface{}, names []string, o *FileOptions) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range names {
+		if err := r.layer(base, name, nil); err != nil {
+			return err
+		}
+	}
+	merged, err := json.Marshal(base)
+	if err != nil {
+		return err
+	}
+	return decodeOptions(merged, o)
+}
+
+// layer merges the preset name, after the presets it extends, into dst
+func (r *PresetRegistry) layer(dst map[string]interface{}, name string, chain []string) error {
+	for _, parent := range chain {
+		if parent == name {
+			return fmt.Errorf("preset %q extends itself through %s", name, strings.Join(chain, " > "))
+		}
+	}
+	preset, ok := r.presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q", name)
+	}
+
+	chain = append(chain, name)
+	for _, parent := range preset.Extends {
+		if err := r.layer(dst, parent, chain); err != nil {
+			return err
+		}
+	}
+	options, err := decodeObject(preset.Options)
+	if err != nil {
+		return err
+	}
+	mergeObject(dst, options)
+	return nil
+}
+
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("options must be a JSON object")
+	}
+	return object, nil
+}
+
+func mergeObject(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if value == nil {
+			delete(dst, key)
+			continue
+		}
+		if object, ok := value.(map[string]interface{}); ok {
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				mergeObject(existing, object)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,380 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/pkg/cmd"
+)
+
+// Prober runs ffprobe on inputs
+type Prober struct {
+	cfg goffmpeg.Configuration
+}
+
+// NewProber returns a prober running the ffprobe of cfg
+func NewProber(cfg goffmpeg.Configuration) *Prober {
+	return &Prober{cfg: cfg}
+}
+
+// ProbeOption limits what a Prober reads from the input
+type ProbeOption func(*probeOptions)
+
+type probeOptions struct {
+	timeout         time.Duration
+	protocols       []string
+	analyzeDuration time.Duration
+	probeSize       int64
+	inputOptions    []string
+	selectStreams   string
+	readIntervals   []string
+	showEntries     string
+	chapters        bool
+	programs        bool
+	countFrames     bool
+}
+
+// WithTimeout kills ffprobe when it runs for longer than timeout, e.g. on an
+// unresponsive network input
+func WithTimeout(timeout time.Duration) ProbeOption {
+	return func(o *probeOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithProtocolWhitelist sets the protocols ffprobe may open, e.g. "file", "http"
+func WithProtocolWhitelist(protocols ...string) ProbeOption {
+	return func(o *probeOptions) {
+		o.protocols = append(o.protocols, protocols...)
+	}
+}
+
+// WithAnalyzeDuration sets how much of the input is analyzed to detect the streams
+func WithAnalyzeDuration(d time.Duration) ProbeOption {
+	return func(o *probeOptions) {
+		o.analyzeDuration = d
+	}
+}
+
+// WithProbeSize sets how many bytes of the input are read to detect the format
+func WithProbeSize(bytes int64) ProbeOption {
+	return func(o *probeOptions) {
+		o.probeSize = bytes
+	}
+}
+
+// WithInputOptions adds options placed before the input, e.g. "-f", "mpegts"
+// or "-headers", "Authorization: Bearer token\r\n"
+func WithInputOptions(args ...string) ProbeOption {
+	return func(o *probeOptions) {
+		o.inputOptions = append(o.inputOptions, args...)
+	}
+}
+
+// WithShowEntries only reads the given entries, in the -show_entries syntax,
+// e.g. "format=duration:stream=index,codec_name". It replaces the format and
+// streams sections read by Probe.
+func WithShowEntries(entries string) ProbeOption {
+	return func(o *probeOptions) {
+		o.showEntries = entries
+	}
+}
+
+// WithChapters also reads the chapters of the input
+func WithChapters() ProbeOption {
+	return func(o *probeOptions) {
+		o.chapters = true
+	}
+}
+
+// WithPrograms also reads the programs of the input
+func WithPrograms() ProbeOption {
+	return func(o *probeOptions) {
+		o.programs = true
+	}
+}
+
+// WithCountFrames decodes the whole input to count the frames of each stream
+func WithCountFrames() ProbeOption {
+	return func(o *probeOptions) {
+		o.countFrames = true
+	}
+}
+
+// WithSelectStreams only reads the streams matching specifier, e.g. "v:0" or "a"
+func WithSelectStreams(specifier string) ProbeOption {
+	return func(o *probeOptions) {
+		o.selectStreams = specifier
+	}
+}
+
+// WithReadIntervals only reads the given intervals, in the -read_intervals
+// syntax, e.g. "01:00%+10" for ten seconds from one minute or "%+#50" for the
+// first fifty packets
+func WithReadIntervals(intervals ...string) ProbeOption {
+	return func(o *probeOptions) {
+		o.readIntervals = append(o.readIntervals, intervals...)
+	}
+}
+
+func newProbeOptions(opts []ProbeOption) probeOptions {
+	var options probeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// context returns the context ffprobe runs with, bounded by the timeout
+func (o probeOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (o probeOptions) args(input string, sections ...string) []string {
+	var args []string
+	if len(o.protocols) > 0 {
+		args = append(args, "-protocol_whitelist", strings.Join(o.protocols, ","))
+	}
+	if o.analyzeDuration > 0 {
+		args = append(args, "-analyzeduration", strconv.FormatInt(o.analyzeDuration.Microseconds(), 10))
+	}
+	if o.probeSize > 0 {
+		args = append(args, "-probesize", strconv.FormatInt(o.probeSize, 10))
+	}
+	args = append(args, o.inputOptions...)
+	if o.selectStreams != "" {
+		args = append(args, "-select_streams", o.selectStreams)
+	}
+	if len(o.readIntervals) > 0 {
+		args = append(args, "-read_intervals", strings.Join(o.readIntervals, ","))
+	}
+	args = append(args, "-i", input, "-print_format", "json")
+	if o.showEntries != "" {
+		args = append(args, "-show_entries", o.showEntries)
+	}
+	args = append(args, sections...)
+	if o.chapters {
+		args = append(args, "-show_chapters")
+	}
+	if o.programs {
+		args = append(args, "-show_programs")
+	}
+	if o.countFrames {
+		args = append(args, "-count_frames")
+	}
+	return append(args, "-show_error")
+}
+
+// Probe reads the format and streams of input, and the sections enabled by opts
+func (p *Prober) Probe(ctx context.Context, input string, opts ...ProbeOption) (*Metadata, error) {
+	options := newProbeOptions(opts)
+	ctx, cancel := options.context(ctx)
+	defer cancel()
+
+	var sections []string
+	if options.showEntries == "" {
+		sections = []string{"-show_format", "-show_streams"}
+	}
+
+	var outb bytes.Buffer
+	errb := cmd.NewTailBuffer(goffmpeg.DefaultStderrLines)
+	proc := p.cfg.Executor().Command(ctx, p.cfg.FFprobeBinPath(), options.args(input, sections...)...)
+	proc.Stdout = &outb
+	proc.Stderr = errb
+
+	if err := proc.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("ffprobe %s: %w", input, ctx.Err())
+		}
+		stderr := errb.Lines()
+		var probeErr probeError
+		if json.Unmarshal(outb.Bytes(), &probeErr) == nil && probeErr.Error != nil {
+			stderr = append(stderr, fmt.Sprintf("%s: %s", input, probeErr.Error.String))
+		}
+		return nil, goffmpeg.NewFFmpegError(proc.Args, err, stderr)
+	}
+
+	metadata := new(Metadata)
+	if err := json.Unmarshal(outb.Bytes(), metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// Frames starts reading the frames of input. The scanner must be read to the
+// end or closed to release ffprobe.
+func (p *Prober) Frames(ctx context.Context, input string, opts ...ProbeOption) (*Scanner[Frame], error) {
+	return startScanner[Frame](ctx, p.cfg, input, "frames", opts)
+}
+
+// Packets starts reading the packets of input. The scanner must be read to the
+// end or closed to release ffprobe.
+func (p *Prober) Packets(ctx context.Context, input string, opts ...ProbeOption) (*Scanner[Packet], error) {
+	return startScanner[Packet](ctx, p.cfg, input, "packets", opts)
+}
+
+// Scanner decodes the entries of a running ffprobe one at a time, so only the
+// current one is held in memory:
+//
+//	for scanner.Scan() {
+//		frame := scanner.Value()
+//	}
+//	err := scanner.Err()
+type Scanner[T any] struct {
+	proc   *exec.Cmd
+	stdout io.ReadCloser
+	stderr *cmd.TailBuffer
+	dec    *json.Decoder
+	ctx    context.Context
+	cancel context.CancelFunc
+	input  string
+	key    string
+
+	inArray  bool
+	probeErr probeError
+	value    T
+	done     bool
+	closed   bool
+	err      error
+}
+
+func startScanner[T any](ctx context.Context, cfg goffmpeg.Configuration, input, key string, opts []ProbeOption) (*Scanner[T], error) {
+	options := newProbeOptions(opts)
+	ctx, cancel := options.context(ctx)
+	s := &Scanner[T]{
+		ctx:    ctx,
+		stderr: cmd.NewTailBuffer(goffmpeg.DefaultStderrLines),
+		cancel: cancel,
+		input:  input,
+		key:    key,
+	}
+	s.proc = cfg.Executor().Command(ctx, cfg.FFprobeBinPath(), options.args(input, "-show_"+key)...)
+	s.proc.Stderr = s.stderr
+
+	var err error
+	if s.stdout, err = s.proc.StdoutPipe(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err = s.proc.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	s.dec = json.NewDecoder(s.stdout)
+	return s, nil
+}
+
+// Scan advances to the next entry, and returns false at the end of the output
+// or on error
+func (s *Scanner[T]) Scan() bool {
+	if s.done {
+		return false
+	}
+	for {
+		if s.inArray {
+			if s.dec.More() {
+				var value T
+				if err := s.dec.Decode(&value); err != nil {
+					s.finish(err)
+					return false
+				}
+				s.value = value
+				return true
+			}
+			s.inArray = false
+		}
+
+		token, err := s.dec.Token()
+		if err == io.EOF {
+			s.finish(nil)
+			return false
+		}
+		if err != nil {
+			s.finish(err)
+			return false
+		}
+
+		switch token {
+		case s.key:
+			if token, err = s.dec.Token(); err != nil || token != json.Delim('[') {
+				s.finish(fmt.Errorf("ffprobe: %s is not an array", s.key))
+				return false
+			}
+			s.inArray = true
+		case "error":
+			if err = s.dec.Decode(&s.probeErr.Error); err != nil {
+				s.finish(err)
+				return false
+			}
+		default:
+			// delimiters of the output object and the arrays, or other sections
+			if _, ok := token.(string); ok {
+				var skipped json.RawMessage
+				if err = s.dec.Decode(&skipped); err != nil {
+					s.finish(err)
+					return false
+				}
+			}
+		}
+	}
+}
+
+// Value Get the entry read by the last call to Scan
+func (s *Scanner[T]) Value() T {
+	return s.value
+}
+
+// Err Get the error that ended the scan, nil at the end of the output or after Close
+func (s *Scanner[T]) Err() error {
+	return s.err
+}
+
+// Close stops ffprobe before the end of the output
+func (s *Scanner[T]) Close() error {
+	if !s.done {
+		s.closed = true
+		s.cancel()
+		s.finish(nil)
+	}
+	return s.err
+}
+
+// finish waits for ffprobe, after a decoding error or the end of its output
+func (s *Scanner[T]) finish(err error) {
+	s.done = true
+	ctxErr := s.ctx.Err()
+	if err != nil {
+		s.cancel()
+	}
+	_, _ = io.Copy(io.Discard, s.stdout)
+	waitErr := s.proc.Wait()
+	s.cancel()
+
+	switch {
+	case s.closed:
+	case ctxErr != nil:
+		s.err = fmt.Errorf("ffprobe %s: %w", s.input, ctxErr)
+	case err != nil:
+		s.err = fmt.Errorf("ffprobe output: %w", err)
+	case waitErr != nil:
+		stderr := s.stderr.Lines()
+		if s.probeErr.Error != nil {
+			stderr = append(stderr, fmt.Sprintf("%s: %s", s.input, s.probeErr.Error.String))
+		}
+		s.err = goffmpeg.NewFFmpegError(s.proc.Args, waitErr, stderr)
+	case s.probeErr.Error != nil:
+		s.err = errors.New(s.input + ": " + s.probeErr.Error.String)
+	}
+}
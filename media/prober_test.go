@@ -0,0 +1,191 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	goffmpegtest.Main(m)
+}
+
+const framesOutput = `{
+    "frames": [
+        {
+            "media_type": "video",
+            "stream_index": 0,
+            "key_frame": 1,
+            "pts": 0,
+            "pts_time": "0.000000",
+            "pkt_dts": 0,
+            "pkt_dts_time": "0.000000",
+            "best_effort_timestamp": 0,
+            "duration": 512,
+            "duration_time": "0.040000",
+            "pkt_pos": "48",
+            "pkt_size": "20463",
+            "width": 1280,
+            "height": 720,
+            "pix_fmt": "yuv420p",
+            "pict_type": "I",
+            "interlaced_frame": 0,
+            "side_data_list": [{"side_data_type": "H.26[45] User Data Unregistered SEI message"}]
+        },
+        {
+            "media_type": "video",
+            "stream_index": 0,
+            "key_frame": 0,
+            "pts": 1536,
+            "pts_time": "0.120000",
+            "pkt_duration_time": "0.040000",
+            "pkt_size": "1311",
+            "pict_type": "B"
+        }
+    ]
+}`
+
+func TestProber(t *testing.T) {
+	t.Run("Should stream the frames of the selected intervals", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = framesOutput
+
+		scanner, err := NewProber(fake.Configuration()).Frames(context.Background(), "in.mp4",
+			WithSelectStreams("v:0"), WithReadIntervals("%+#2", "60%+5"))
+		require.NoError(t, err)
+
+		var frames []Frame
+		for scanner.Scan() {
+			frames = append(frames, scanner.Value())
+		}
+		require.NoError(t, scanner.Err())
+		require.Len(t, frames, 2)
+
+		require.True(t, frames[0].KeyFrame)
+		require.Equal(t, "I", frames[0].PictType)
+		require.Equal(t, int64(48), frames[0].Pos)
+		require.Equal(t, 20463, frames[0].Size)
+		require.Equal(t, 40*time.Millisecond, frames[0].Duration)
+
+		require.False(t, frames[1].KeyFrame)
+		require.Equal(t, 120*time.Millisecond, frames[1].PTSTime)
+		require.Equal(t, NoPTS, frames[1].DTS)
+		require.Equal(t, int64(-1), frames[1].Pos)
+		require.Equal(t, 40*time.Millisecond, frames[1].Duration)
+
+		require.Equal(t, []string{
+			"-select_streams", "v:0", "-read_intervals", "%+#2,60%+5",
+			"-i", "in.mp4", "-print_format", "json", "-show_frames", "-show_error",
+		}, fake.LastCall("ffprobe").Args)
+	})
+
+	t.Run("Should stream the packets", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"packets": [
+			{"codec_type": "audio", "stream_index": 1, "pts": 1024, "pts_time": "0.021333", "dts": 1024, "dts_time": "0.021333", "size": "371", "pos": "20511", "flags": "K__"},
+			{"codec_type": "video", "stream_index": 0, "pts": 1536, "dts": 512, "dts_time": "0.040000", "size": "1311", "flags": "___"}
+		]}`
+
+		scanner, err := NewProber(fake.Configuration()).Packets(context.Background(), "in.mp4")
+		require.NoError(t, err)
+
+		require.True(t, scanner.Scan())
+		require.Equal(t, Packet{
+			CodecType: CodecTypeAudio, StreamIndex: 1,
+			PTS: 1024, PTSTimeStr: "0.021333", PTSTime: 21333 * time.Microsecond,
+			DTS: 1024, DTSTimeStr: "0.021333", DTSTime: 21333 * time.Microsecond,
+			SizeStr: "371", Size: 371, PosStr: "20511", Pos: 20511, Flags: "K__", KeyFrame: true,
+		}, scanner.Value())
+		require.True(t, scanner.Scan())
+		require.False(t, scanner.Value().KeyFrame)
+		require.Equal(t, 40*time.Millisecond, scanner.Value().DTSTime)
+		require.False(t, scanner.Scan())
+		require.NoError(t, scanner.Err())
+	})
+
+	t.Run("Should report the error of ffprobe", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"frames": [], "error": {"code": -2, "string": "No such file or directory"}}`
+		fake.FFprobe.ExitCode = 1
+
+		scanner, err := NewProber(fake.Configuration()).Frames(context.Background(), "missing.mp4")
+		require.NoError(t, err)
+		require.False(t, scanner.Scan())
+		require.True(t, errors.Is(scanner.Err(), goffmpeg.ErrInputNotFound))
+	})
+
+	t.Run("Should stop ffprobe when closed early", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = framesOutput
+		fake.FFprobe.Hang = true
+
+		scanner, err := NewProber(fake.Configuration()).Frames(context.Background(), "in.mp4")
+		require.NoError(t, err)
+		require.True(t, scanner.Scan())
+		require.NoError(t, scanner.Close())
+		require.False(t, scanner.Scan())
+	})
+}
+
+func TestProbe(t *testing.T) {
+	t.Run("Should read the sections enabled by the options", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{
+			"streams": [{"index": 0, "codec_name": "h264", "codec_type": "video", "nb_frames": "250", "nb_read_frames": "250"}],
+			"chapters": [{"id": 0, "time_base": "1/1000", "start": 0, "start_time": "0.000000", "end": 90500, "end_time": "90.500000", "tags": {"title": "Intro"}}],
+			"programs": [{"program_id": 1, "program_num": 1, "nb_streams": 1, "pmt_pid": 4096, "pcr_pid": 256, "streams": [{"index": 0, "codec_type": "video"}]}],
+			"format": {"filename": "in.ts", "duration": "90.500000"}
+		}`
+
+		metadata, err := NewProber(fake.Configuration()).Probe(context.Background(), "in.ts",
+			WithInputOptions("-f", "mpegts"), WithAnalyzeDuration(5*time.Second), WithProbeSize(1<<20),
+			WithChapters(), WithPrograms(), WithCountFrames())
+		require.NoError(t, err)
+		require.Equal(t, "250", metadata.Streams[0].NbReadFrames)
+		require.Equal(t, "Intro", metadata.Chapters[0].Title())
+		require.Equal(t, 90500*time.Millisecond, metadata.Chapters[0].EndTime)
+		require.Equal(t, 256, metadata.Programs[0].PcrPid)
+		require.Equal(t, CodecTypeVideo, metadata.Programs[0].Streams[0].CodecType)
+		require.Equal(t, 90500*time.Millisecond, metadata.Format.Duration)
+
+		require.Equal(t, []string{
+			"-analyzeduration", "5000000", "-probesize", "1048576", "-f", "mpegts",
+			"-i", "in.ts", "-print_format", "json", "-show_format", "-show_streams",
+			"-show_chapters", "-show_programs", "-count_frames", "-show_error",
+		}, fake.LastCall("ffprobe").Args)
+	})
+
+	t.Run("Should only request the selected entries", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"format": {"duration": "10.000000"}}`
+
+		metadata, err := NewProber(fake.Configuration()).Probe(context.Background(), "in.mp4", WithShowEntries("format=duration"))
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Second, metadata.Format.Duration)
+		require.Equal(t, []string{"-i", "in.mp4", "-print_format", "json", "-show_entries", "format=duration", "-show_error"}, fake.LastCall("ffprobe").Args)
+	})
+
+	t.Run("Should give up on an unresponsive input after the timeout", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Hang = true
+
+		_, err := NewProber(fake.Configuration()).Probe(context.Background(), "http://example.com/live.m3u8", WithTimeout(100*time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Should keep the command of NewMetadata", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"streams": [], "format": {}}`
+
+		_, err := NewMetadata(fake.Configuration(), "in.mp4", "file", "http")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"-protocol_whitelist", "file,http", "-i", "in.mp4", "-print_format", "json", "-show_format", "-show_streams", "-show_error",
+		}, fake.LastCall("ffprobe").Args)
+	})
+}
@@ -36,7 +36,12 @@ type Stream struct {
 	DurationTs         int         `json:"duration_ts"`
 	Duration           string      `json:"duration"`
 	BitRate            string      `json:"bit_rate"`
+	NbFrames           string      `json:"nb_frames"`
+	NbReadFrames       string      `json:"nb_read_frames"` // read WithCountFrames
 	Channels           int         `json:"channels"`
+	ChannelLayout      string      `json:"channel_layout"`
+	SampleRate         string      `json:"sample_rate"`
+	SampleFmt          string      `json:"sample_fmt"`
 	Disposition        Disposition `json:"disposition"`
 	SideDataList       []SideData  `json:"side_data_list"`
 	Tags               *StreamTags `json:"tags"`
@@ -126,6 +131,18 @@ func (s Stream) IsAudio() bool {
 	return s.CodecType == CodecTypeAudio
 }
 
+func (s Stream) IsSubtitle() bool {
+	return s.CodecType == CodecTypeSubtitle
+}
+
+// Language returns the language tag of the stream, or an empty string
+func (s Stream) Language() string {
+	if s.Tags == nil || s.Tags.Language == nil {
+		return ""
+	}
+	return *s.Tags.Language
+}
+
 func (s Stream) Orientation() *Orientation {
 	if !s.IsVideo() || s.Width == 0 || s.Height == 0 {
 		return nil
@@ -0,0 +1,234 @@
+package media
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/graux/goffmpeg/pkg/filtergraph"
+)
+
+// SubtitleFormat is a text subtitle format ffmpeg can read and write
+type SubtitleFormat string
+
+const (
+	SubtitleFormatSRT    SubtitleFormat = "srt"
+	SubtitleFormatWebVTT SubtitleFormat = "webvtt"
+	SubtitleFormatASS    SubtitleFormat = "ass"
+)
+
+// SubtitleFormatFromPath returns the subtitle format matching the extension of path
+func SubtitleFormatFromPath(path string) (SubtitleFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt":
+		return SubtitleFormatSRT, nil
+	case ".vtt", ".webvtt":
+		return SubtitleFormatWebVTT, nil
+	case ".ass", ".ssa":
+		return SubtitleFormatASS, nil
+	default:
+		return "", fmt.Errorf("unknown subtitle format for %s", path)
+	}
+}
+
+// SubtitleCodecFor returns the subtitle codec a container needs, or an empty
+// string to let ffmpeg choose: mov_text for MP4 and webvtt for HLS and WebM
+func SubtitleCodecFor(outputPath, outputFormat string) string {
+	switch outputFormat {
+	case "mp4", "mov", "ipod":
+		return "mov_text"
+	case "hls", "webm", "webvtt":
+		return "webvtt"
+	}
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".mp4", ".m4v", ".mov":
+		return "mov_text"
+	case ".m3u8", ".webm", ".vtt":
+		return "webvtt"
+	case ".srt":
+		return "srt"
+	case ".ass", ".ssa":
+		return "ass"
+	}
+	return ""
+}
+
+// BurnSubtitles renders a subtitle file, or a subtitle stream of a media file,
+// into the video
+type BurnSubtitles struct {
+	Path        string `json:"path,omitempty"`
+	StreamIndex int    `json:"stream_index,omitempty"` // subtitle stream of Path to render, when Path has several
+	ForceStyle  string `json:"force_style,omitempty"`  // ASS style overrides, e.g. "FontSize=24,PrimaryColour=&H00FFFF"
+	CharEnc     string `json:"char_enc,omitempty"`
+}
+
+// Filter returns the subtitles filter, escaped for a filtergraph
+func (b BurnSubtitles) Filter() string {
+	filter := filtergraph.F("subtitles").Set("filename", b.Path)
+	if b.StreamIndex > 0 {
+		filter = filter.Set("si", strconv.Itoa(b.StreamIndex))
+	}
+	if b.ForceStyle != "" {
+		filter = filter.Set("force_style", b.ForceStyle)
+	}
+	if b.CharEnc != "" {
+		filter = filter.Set("charenc", b.CharEnc)
+	}
+	return filter.String()
+}
+
+// SubtitleTrack is a soft subtitle stream muxed into the output
+type SubtitleTrack struct {
+	Input    int    `json:"input,omitempty"`  // ffmpeg input index
+	Stream   int    `json:"stream,omitempty"` // subtitle stream index within the input
+	Language string `json:"language,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+	Forced   bool   `json:"forced,omitempty"`
+	Codec    string `json:"codec,omitempty"` // picked from the output container when empty
+}
+
+func (s SubtitleTrack) disposition() string {
+	var flags []string
+	if s.Default {
+		flags = append(flags, "default")
+	}
+	if s.Forced {
+		flags = append(flags, "forced")
+	}
+	if len(flags) == 0 {
+		return "0"
+	}
+	return strings.Join(flags, "+")
+}
+
+// AddSubtitleFile adds a subtitle file as an input and muxes its first stream as a track
+func (m *File) AddSubtitleFile(path string, track SubtitleTrack) int {
+	track.Input = m.AddInput(&Input{Path: path})
+	track.Stream = 0
+	m.AddSubtitleTrack(track)
+	return track.Input
+}
+
+// AddSubtitleTrack muxes a subtitle stream of an input into the output. When no
+// -map was added, the video and audio of the first input are mapped as well.
+func (m *File) AddSubtitleTrack(track SubtitleTrack) {
+	m.subtitleTracks = append(m.subtitleTracks, track)
+}
+
+func (m *File) SubtitleTracks() []SubtitleTrack {
+	return m.subtitleTracks
+}
+
+func (m *File) SetBurnSubtitles(v *BurnSubtitles) {
+	m.burnSubtitles = v
+}
+
+func (m *File) BurnSubtitles() *BurnSubtitles {
+	return m.burnSubtitles
+}
+
+func (m *File) ObtainSubtitleTracks() []string {
+	if len(m.subtitleTracks) == 0 {
+		return nil
+	}
+
+	var result []string
+	if len(m.maps) == 0 {
+		result = append(result, "-map", MapType(0, StreamTypeVideo).AsOptional().String(),
+			"-map", MapType(0, StreamTypeAudio).AsOptional().String())
+	}
+	// the tracks follow the subtitle streams already selected by the maps
+	offset, _ := m.mappedSubtitleStreams()
+	for i, track := range m.subtitleTracks {
+		index := strconv.Itoa(offset + i)
+		result = append(result, "-map", MapTypeIndex(track.Input, StreamTypeSubtitle, track.Stream).String())
+
+		codec := track.Codec
+		if codec == "" {
+			codec = SubtitleCodecFor(m.outputPath, m.outputFormat)
+		}
+		if codec != "" {
+			result = append(result, "-c:s:"+index, codec)
+		}
+		if track.Language != "" {
+			result = append(result, "-metadata:s:s:"+index, "language="+track.Language)
+		}
+		if track.Title != "" {
+			result = append(result, "-metadata:s:s:"+index, "title="+track.Title)
+		}
+		result = append(result, "-disposition:s:"+index, track.disposition())
+	}
+	return result
+}
+
+// ValidateSubtitleTracks checks that the output index of the soft tracks of the
+// File and its outputs is known, which needs the metadata of every input mapped
+// with its subtitles
+func (m *File) ValidateSubtitleTracks() error {
+	for _, file := range append([]*File{m}, m.outputs...) {
+		if len(file.subtitleTracks) == 0 {
+			continue
+		}
+		if _, ok := file.mappedSubtitleStreams(); !ok {
+			return fmt.Errorf("subtitle tracks of %s: the metadata of an input mapped with its subtitle streams is unknown", file.outputPath)
+		}
+	}
+	return nil
+}
+
+// mappedSubtitleStreams returns the number of subtitle streams selected by the
+// maps, and false when the metadata of an input they may select from is unknown
+func (m *File) mappedSubtitleStreams() (int, bool) {
+	type mapped struct{ input, stream int }
+	var streams []mapped
+	for _, spec := range m.maps {
+		if spec.Type != "" && spec.Type != StreamTypeSubtitle {
+			continue
+		}
+		metadata := m.InputMetadata(spec.Input)
+		if metadata == nil {
+			return 0, false
+		}
+
+		typeIndex := -1
+		for _, stream := range metadata.Streams {
+			if stream.CodecType != CodecTypeSubtitle {
+				continue
+			}
+			typeIndex++
+			if !spec.selectsSubtitle(stream, typeIndex) {
+				continue
+			}
+			selected := mapped{spec.Input, stream.Index}
+			if !spec.Negative {
+				streams = append(streams, selected)
+				continue
+			}
+			kept := streams[:0]
+			for _, s := range streams {
+				if s != selected {
+					kept = append(kept, s)
+				}
+			}
+			streams = kept
+		}
+	}
+	return len(streams), true
+}
+
+// selectsSubtitle reports whether s selects stream, the subtitle stream of
+// its input at typeIndex
+func (s StreamSpecifier) selectsSubtitle(stream Stream, typeIndex int) bool {
+	switch {
+	case s.Language != "":
+		return stream.Language() == s.Language
+	case s.Index == AnyIndex:
+		return true
+	case s.Type == StreamTypeSubtitle:
+		return typeIndex == s.Index
+	default:
+		return stream.Index == s.Index
+	}
+}
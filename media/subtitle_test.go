@@ -0,0 +1,85 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtitles(t *testing.T) {
+	t.Run("Should escape the path of burnt subtitles", func(t *testing.T) {
+		file := &File{}
+		file.SetVideoFilter("scale=1280:-2")
+		file.SetBurnSubtitles(&BurnSubtitles{Path: `C:\subs\it's.srt`, ForceStyle: "FontSize=24"})
+		require.Equal(t, []string{"-vf", `scale=1280:-2,subtitles=filename=C\\:\\\\subs\\\\it\\\'s.srt:force_style=FontSize=24`}, file.ObtainVideoFilter())
+	})
+
+	t.Run("Should mux soft tracks with the codec of the container", func(t *testing.T) {
+		file := &File{}
+		file.SetInputPath("in.mp4")
+		file.SetOutputPath("out.mp4")
+		require.Equal(t, 1, file.AddSubtitleFile("en.srt", SubtitleTrack{Language: "eng", Default: true}))
+		file.AddSubtitleTrack(SubtitleTrack{Input: 0, Stream: 1, Language: "spa", Forced: true})
+
+		require.Equal(t, []string{
+			"-map", "0:v?", "-map", "0:a?",
+			"-map", "1:s:0", "-c:s:0", "mov_text", "-metadata:s:s:0", "language=eng", "-disposition:s:0", "default",
+			"-map", "0:s:1", "-c:s:1", "mov_text", "-metadata:s:s:1", "language=spa", "-disposition:s:1", "forced",
+		}, file.ObtainSubtitleTracks())
+	})
+
+	t.Run("Should number soft tracks after the mapped subtitle streams", func(t *testing.T) {
+		file := &File{}
+		file.SetMetadata(&Metadata{Streams: []Stream{
+			{Index: 0, CodecType: CodecTypeVideo},
+			{Index: 1, CodecType: CodecTypeSubtitle},
+			{Index: 2, CodecType: CodecTypeSubtitle},
+		}})
+		file.SetInputPath("in.mkv")
+		file.SetOutputPath("out.mkv")
+		file.AddMap(MapInput(0), MapTypeIndex(0, StreamTypeSubtitle, 1).Negated())
+		file.AddSubtitleFile("en.srt", SubtitleTrack{Language: "eng", Codec: "srt"})
+
+		require.NoError(t, file.ValidateSubtitleTracks())
+		require.Equal(t, []string{
+			"-map", "1:s:0", "-c:s:1", "srt", "-metadata:s:s:1", "language=eng", "-disposition:s:1", "0",
+		}, file.ObtainSubtitleTracks())
+	})
+
+	t.Run("Should reject tracks after maps of an input without metadata", func(t *testing.T) {
+		file := &File{}
+		file.SetInputPath("in.mkv")
+		file.AddMap(MapInput(0))
+		file.AddSubtitleFile("en.srt", SubtitleTrack{Language: "eng"})
+		require.Error(t, file.ValidateSubtitleTracks())
+
+		file = &File{}
+		file.SetInputPath("in.mkv")
+		file.AddMap(MapType(0, StreamTypeVideo), MapType(0, StreamTypeAudio))
+		file.AddSubtitleFile("en.srt", SubtitleTrack{Language: "eng"})
+		require.NoError(t, file.ValidateSubtitleTracks())
+	})
+
+	t.Run("Should reject broken tracks of additional outputs", func(t *testing.T) {
+		file := &File{}
+		file.SetInputPath("in.mkv")
+		file.SetOutputPath("out.mp4")
+		output := &File{}
+		output.SetOutputPath("out.mkv")
+		output.AddMap(MapInput(0))
+		output.AddSubtitleTrack(SubtitleTrack{Input: 1, Language: "eng"})
+		file.AddOutput(output)
+		require.ErrorContains(t, file.ValidateSubtitleTracks(), "subtitle tracks of out.mkv")
+	})
+
+	t.Run("Should pick the subtitle codec of the output", func(t *testing.T) {
+		require.Equal(t, "webvtt", SubtitleCodecFor("index.m3u8", ""))
+		require.Equal(t, "webvtt", SubtitleCodecFor("out", "hls"))
+		require.Equal(t, "mov_text", SubtitleCodecFor("out.m4v", ""))
+		require.Equal(t, "", SubtitleCodecFor("out.mkv", ""))
+
+		format, err := SubtitleFormatFromPath("out.VTT")
+		require.NoError(t, err)
+		require.Equal(t, SubtitleFormatWebVTT, format)
+	})
+}
@@ -1,35 +1,29 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 )
 
-func FindBinPath(ctx context.Context, command string) (string, error) {
+// LookPath resolves command in each of dirs, in order, and then in the PATH
+func LookPath(command string, dirs ...string) (string, error) {
 	if command == "" {
 		return "", fmt.Errorf("command cannot be empty")
 	}
 
-	path, err := execBufferOutput(ctx, getFindCommand(), command)
-	if err != nil {
-		return "", err
+	for _, dir := range dirs {
+		if path, err := exec.LookPath(filepath.Join(dir, command)); err == nil {
+			return path, nil
+		}
 	}
-
-	return path, nil
+	return exec.LookPath(command)
 }
 
-func execBufferOutput(ctx context.Context, command string, args ...string) (string, error) {
-	var out bytes.Buffer
-
-	c := exec.CommandContext(ctx, command, args...)
-	c.Stdout = &out
-
-	err := c.Run()
-	if err != nil {
-		return "", fmt.Errorf("%s: %s", c.String(), err)
-	}
-
-	return out.String(), nil
+// FindBinPath resolves command in the PATH.
+//
+// Deprecated: use LookPath, which also searches given directories first.
+func FindBinPath(ctx context.Context, command string) (string, error) {
+	return LookPath(command)
 }
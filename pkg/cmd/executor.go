@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// Executor creates the processes goffmpeg runs. Implementations may rewrite the
+// command, e.g. to run it in a container or under nice, as long as the returned
+// command is not started yet.
+type Executor interface {
+	Command(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+// ExecutorFunc adapts a function to the Executor interface
+type ExecutorFunc func(ctx context.Context, name string, args ...string) *exec.Cmd
+
+func (f ExecutorFunc) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return f(ctx, name, args...)
+}
+
+// DefaultExecutor runs commands with os/exec, killing them when ctx is done
+var DefaultExecutor Executor = ExecutorFunc(exec.CommandContext)
+
+// WithPrefix runs every command of e through prefix, e.g.
+// WithPrefix(e, "nice", "-n", "10") or WithPrefix(e, "docker", "exec", "ffmpeg")
+func WithPrefix(e Executor, prefix ...string) Executor {
+	if len(prefix) == 0 {
+		return e
+	}
+	return ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		prefixed := append(append(append([]string{}, prefix[1:]...), name), args...)
+		return e.Command(ctx, prefix[0], prefixed...)
+	})
+}
+
+// WithEnv adds env, as "KEY=value" entries, to the environment of every command
+// of e. Commands without an environment inherit the one of the current process.
+func WithEnv(e Executor, env ...string) Executor {
+	return ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		c := e.Command(ctx, name, args...)
+		if c.Env == nil {
+			c.Env = os.Environ()
+		}
+		c.Env = append(c.Env, env...)
+		return c
+	})
+}
+
+// WithDir runs every command of e in dir, unless it already has a working directory
+func WithDir(e Executor, dir string) Executor {
+	return ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		c := e.Command(ctx, name, args...)
+		if c.Dir == "" {
+			c.Dir = dir
+		}
+		return c
+	})
+}
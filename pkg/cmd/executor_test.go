@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor(t *testing.T) {
+	t.Run("Should place the prefix of the wrapped executor first", func(t *testing.T) {
+		executor := WithPrefix(WithPrefix(DefaultExecutor, "taskset", "-c", "0"), "nice", "-n", "10")
+
+		c := executor.Command(context.Background(), "ffmpeg", "-i", "in.mp4")
+		require.Equal(t, []string{"taskset", "-c", "0", "nice", "-n", "10", "ffmpeg", "-i", "in.mp4"}, c.Args)
+	})
+
+	t.Run("Should add the environment to the inherited one", func(t *testing.T) {
+		t.Setenv("GOFFMPEG_TEST_INHERITED", "1")
+		executor := WithEnv(DefaultExecutor, "AV_LOG_FORCE_NOCOLOR=1")
+
+		c := executor.Command(context.Background(), "ffmpeg")
+		require.Contains(t, c.Env, "GOFFMPEG_TEST_INHERITED=1")
+		require.Equal(t, "AV_LOG_FORCE_NOCOLOR=1", c.Env[len(c.Env)-1])
+	})
+
+	t.Run("Should keep the working directory set by the wrapped executor", func(t *testing.T) {
+		c := WithDir(WithDir(DefaultExecutor, "/inner"), "/outer").Command(context.Background(), "ffmpeg")
+		require.Equal(t, "/inner", c.Dir)
+	})
+}
+
+func TestLookPath(t *testing.T) {
+	t.Run("Should look in the directories before the PATH", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"), 0o755))
+		t.Setenv("PATH", t.TempDir())
+
+		path, err := LookPath("ffmpeg", t.TempDir(), dir)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, "ffmpeg"), path)
+
+		_, err = LookPath("ffprobe", dir)
+		require.ErrorIs(t, err, exec.ErrNotFound)
+	})
+
+	t.Run("Should keep resolving with the deprecated FindBinPath", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"), 0o755))
+		t.Setenv("PATH", dir)
+
+		path, err := FindBinPath(context.Background(), "ffmpeg")
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, "ffmpeg"), path)
+	})
+}
@@ -1,14 +0,0 @@
-package cmd
-
-import "runtime"
-
-var platform = runtime.GOOS
-
-func getFindCommand() string {
-	switch platform {
-	case "windows":
-		return "where"
-	default:
-		return "which"
-	}
-}
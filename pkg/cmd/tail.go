@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// TailBuffer is a writer keeping only the last lines written to it
+type TailBuffer struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func NewTailBuffer(maxLines int) *TailBuffer {
+	return &TailBuffer{max: maxLines}
+}
+
+func (b *TailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := append(b.partial, p...)
+	for {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			break
+		}
+		b.push(string(data[:i]))
+		data = data[i+1:]
+	}
+	b.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+func (b *TailBuffer) push(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	b.lines = append(b.lines, line)
+	if len(b.lines) > b.max {
+		b.lines = b.lines[len(b.lines)-b.max:]
+	}
+}
+
+// Lines returns the kept lines, including an unterminated last one
+func (b *TailBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := append([]string(nil), b.lines...)
+	if len(strings.TrimSpace(string(b.partial))) > 0 {
+		lines = append(lines, string(b.partial))
+		if len(lines) > b.max {
+			lines = lines[len(lines)-b.max:]
+		}
+	}
+	return lines
+}
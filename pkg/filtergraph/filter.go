@@ -0,0 +1,94 @@
+package filtergraph
+
+import "strings"
+
+// Filter is a single filter with its arguments
+type Filter struct {
+	Name    string
+	Args    []string
+	Options []Option
+}
+
+// Option is a key=value filter argument
+type Option struct {
+	Key   string
+	Value string
+}
+
+// F returns a filter with positional arguments
+func F(name string, args ...string) Filter {
+	return Filter{Name: name, Args: args}
+}
+
+// Set returns a copy of the filter with a key=value argument appended
+func (f Filter) Set(key, value string) Filter {
+	f.Options = append(append([]Option(nil), f.Options...), Option{Key: key, Value: value})
+	return f
+}
+
+// String returns the filter with its arguments escaped for a filtergraph
+func (f Filter) String() string {
+	args := make([]string, 0, len(f.Args)+len(f.Options))
+	for _, arg := range f.Args {
+		args = append(args, Escape(arg))
+	}
+	for _, option := range f.Options {
+		args = append(args, option.Key+"="+Escape(option.Value))
+	}
+	if len(args) == 0 {
+		return f.Name
+	}
+	return f.Name + "=" + strings.Join(args, ":")
+}
+
+var (
+	optionEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`)
+	graphEscaper  = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`)
+)
+
+// Escape escapes a filter argument twice: for the filter option parser and for
+// the filtergraph parser
+func Escape(value string) string {
+	return graphEscaper.Replace(optionEscaper.Replace(value))
+}
+
+// FilterNames returns the names of the filters of a textual filtergraph, such
+// as a -vf value, in order and without instance names
+func FilterNames(graph string) []string {
+	var names []string
+	for _, filter := range splitFilters(graph) {
+		filter = strings.TrimSpace(filter)
+		for strings.HasPrefix(filter, "[") {
+			end := strings.IndexByte(filter, ']')
+			if end < 0 {
+				break
+			}
+			filter = strings.TrimSpace(filter[end+1:])
+		}
+		if end := strings.IndexAny(filter, "=@[ \t\n"); end >= 0 {
+			filter = filter[:end]
+		}
+		if filter != "" {
+			names = append(names, filter)
+		}
+	}
+	return names
+}
+
+// splitFilters splits graph on the commas and semicolons outside quotes and escapes
+func splitFilters(graph string) []string {
+	var filters []string
+	start, quoted := 0, false
+	for i := 0; i < len(graph); i++ {
+		switch c := graph[i]; {
+		case c == '\\':
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case !quoted && (c == ',' || c == ';'):
+			filters = append(filters, graph[start:i])
+			start = i + 1
+		}
+	}
+	return append(filters, graph[start:])
+}
@@ -0,0 +1,181 @@
+// Package filtergraph builds ffmpeg -filter_complex descriptions.
+package filtergraph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Graph is a set of filter chains linked by pads
+type Graph struct {
+	chains  []*Chain
+	outputs []*Pad
+	labels  int
+}
+
+// Pad is a link label between chains, or an input stream of ffmpeg
+type Pad struct {
+	graph    *Graph
+	label    string
+	stream   bool
+	producer *Chain
+	uses     int
+}
+
+// Chain is a sequence of filters, each one feeding the next
+type Chain struct {
+	graph   *Graph
+	inputs  []*Pad
+	filters []Filter
+	outputs []*Pad
+}
+
+// New returns an empty Graph
+func New() *Graph {
+	return &Graph{}
+}
+
+// Input returns a pad reading an ffmpeg input stream, e.g. "0:v" or "1:a:0".
+// Input streams can feed several chains.
+func (g *Graph) Input(stream string) *Pad {
+	return &Pad{graph: g, label: stream, stream: true}
+}
+
+// Chain appends a chain of filters reading from inputs
+func (g *Graph) Chain(inputs []*Pad, filters ...Filter) *Chain {
+	chain := &Chain{graph: g, inputs: inputs, filters: filters}
+	for _, pad := range inputs {
+		if pad != nil {
+			pad.uses++
+		}
+	}
+	g.chains = append(g.chains, chain)
+	return chain
+}
+
+// Output marks pad as an output of the graph and returns its -map value
+func (g *Graph) Output(pad *Pad) string {
+	pad.uses++
+	g.outputs = append(g.outputs, pad)
+	return "[" + pad.label + "]"
+}
+
+// Out adds an output pad with an automatic label
+func (c *Chain) Out() *Pad {
+	label := fmt.Sprintf("l%d", c.graph.labels)
+	c.graph.labels++
+	return c.NamedOut(label)
+}
+
+// NamedOut adds an output pad with the given label
+func (c *Chain) NamedOut(label string) *Pad {
+	pad := &Pad{graph: c.graph, label: label, producer: c}
+	c.outputs = append(c.outputs, pad)
+	return pad
+}
+
+// Outs adds n output pads with automatic labels, e.g. for split
+func (c *Chain) Outs(n int) []*Pad {
+	pads := make([]*Pad, n)
+	for i := range pads {
+		pads[i] = c.Out()
+	}
+	return pads
+}
+
+// Label returns the label of the pad
+func (p *Pad) Label() string {
+	return p.label
+}
+
+// Validate checks every pad belongs to the graph and is used exactly once
+func (g *Graph) Validate() error {
+	var errs []error
+	labels := make(map[string]bool)
+
+	for i, chain := range g.chains {
+		if len(chain.filters) == 0 {
+			errs = append(errs, fmt.Errorf("chain %d has no filters", i))
+		}
+		for _, pad := range chain.inputs {
+			switch {
+			case pad == nil:
+				errs = append(errs, fmt.Errorf("chain %d has a nil input pad", i))
+			case pad.graph != g:
+				errs = append(errs, fmt.Errorf("pad [%s] belongs to another graph", pad.label))
+			}
+		}
+		for _, pad := range chain.outputs {
+			if labels[pad.label] {
+				errs = append(errs, fmt.Errorf("label [%s] is defined twice", pad.label))
+			}
+			labels[pad.label] = true
+
+			switch {
+			case pad.uses == 0:
+				errs = append(errs, fmt.Errorf("pad [%s] is not connected", pad.label))
+			case pad.uses > 1:
+				errs = append(errs, fmt.Errorf("pad [%s] is used %d times", pad.label, pad.uses))
+			}
+		}
+	}
+
+	for _, pad := range g.outputs {
+		if pad.graph != g {
+			errs = append(errs, fmt.Errorf("pad [%s] belongs to another graph", pad.label))
+		} else if pad.stream {
+			errs = append(errs, fmt.Errorf("input stream [%s] cannot be a graph output", pad.label))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// String returns the -filter_complex description
+func (g *Graph) String() string {
+	chains := make([]string, len(g.chains))
+	for i, chain := range g.chains {
+		chains[i] = chain.String()
+	}
+	return strings.Join(chains, ";")
+}
+
+func (c *Chain) String() string {
+	var desc strings.Builder
+	for _, pad := range c.inputs {
+		if pad != nil {
+			desc.WriteString("[" + pad.label + "]")
+		}
+	}
+	filters := make([]string, len(c.filters))
+	for i, filter := range c.filters {
+		filters[i] = filter.String()
+	}
+	desc.WriteString(strings.Join(filters, ","))
+	for _, pad := range c.outputs {
+		desc.WriteString("[" + pad.label + "]")
+	}
+	return desc.String()
+}
+
+// OutputLabels returns the -map value of every graph output, in order
+func (g *Graph) OutputLabels() []string {
+	labels := make([]string, len(g.outputs))
+	for i, pad := range g.outputs {
+		labels[i] = "[" + pad.label + "]"
+	}
+	return labels
+}
+
+// Args validates the graph and returns -filter_complex with a -map per output
+func (g *Graph) Args() ([]string, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	args := []string{"-filter_complex", g.String()}
+	for _, label := range g.OutputLabels() {
+		args = append(args, "-map", label)
+	}
+	return args, nil
+}
@@ -0,0 +1,58 @@
+package filtergraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	t.Run("Should build a watermark graph with automatic labels", func(t *testing.T) {
+		g := New()
+		scaled := g.Chain([]*Pad{g.Input("0:v")}, F("scale", "1280", "-2")).Out()
+		logo := g.Chain([]*Pad{g.Input("1:v")}, F("format", "rgba"), F("colorchannelmixer").Set("aa", "0.5")).Out()
+		out := g.Chain([]*Pad{scaled, logo}, F("overlay").Set("x", "W-w-10").Set("y", "10")).NamedOut("vout")
+		require.Equal(t, "[vout]", g.Output(out))
+
+		args, err := g.Args()
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"-filter_complex",
+			"[0:v]scale=1280:-2[l0];[1:v]format=rgba,colorchannelmixer=aa=0.5[l1];[l0][l1]overlay=x=W-w-10:y=10[vout]",
+			"-map", "[vout]",
+		}, args)
+	})
+
+	t.Run("Should reject unconnected pads and pads used twice", func(t *testing.T) {
+		g := New()
+		split := g.Chain([]*Pad{g.Input("0:v")}, F("split")).Outs(2)
+		g.Chain([]*Pad{split[0]}, F("hflip")).Out()
+		g.Output(split[0])
+
+		err := g.Validate()
+		require.ErrorContains(t, err, "pad [l0] is used 2 times")
+		require.ErrorContains(t, err, "pad [l1] is not connected")
+		require.ErrorContains(t, err, "pad [l2] is not connected")
+	})
+
+	t.Run("Should reject pads of another graph", func(t *testing.T) {
+		other := New().Chain([]*Pad{New().Input("0:v")}, F("null")).Out()
+		g := New()
+		g.Chain([]*Pad{other}, F("null"))
+
+		require.ErrorContains(t, g.Validate(), "pad [l0] belongs to another graph")
+	})
+}
+
+func TestEscape(t *testing.T) {
+	require.Equal(t, `C\\:\\\\subs\\\\it\\\'s.srt`, Escape(`C:\subs\it's.srt`))
+	require.Equal(t, `a\,b\;c\[d\]`, Escape("a,b;c[d]"))
+	require.Equal(t, `drawtext=text=10\\:00`, F("drawtext").Set("text", "10:00").String())
+}
+
+func TestFilterNames(t *testing.T) {
+	t.Run("Should list filters without labels, options and instance names", func(t *testing.T) {
+		graph := `[0:v]scale=1280:-2,drawtext=text='a\, b; c':x=10[v];[v][1:v] overlay@logo=10:10 [out]; anullsrc`
+		require.Equal(t, []string{"scale", "drawtext", "overlay", "anullsrc"}, FilterNames(graph))
+	})
+}
@@ -0,0 +1,93 @@
+package m3u8
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Attribute is one entry of a tag attribute list, e.g. CODECS="avc1.640028"
+type Attribute struct {
+	Key    string
+	Value  string
+	Quoted bool
+}
+
+func (a Attribute) String() string {
+	if a.Quoted {
+		return a.Key + `="` + a.Value + `"`
+	}
+	return a.Key + "=" + a.Value
+}
+
+// parseAttributes splits an attribute list on the commas outside quoted strings
+func parseAttributes(list string) []Attribute {
+	var attributes []Attribute
+	for list != "" {
+		key, rest, found := strings.Cut(list, "=")
+		if !found {
+			break
+		}
+		attribute := Attribute{Key: strings.TrimSpace(key)}
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			attribute.Value, attribute.Quoted = rest[1:end+1], true
+			rest = rest[end+1:]
+			rest = strings.TrimPrefix(rest, `"`)
+		} else if comma := strings.IndexByte(rest, ','); comma >= 0 {
+			attribute.Value, rest = rest[:comma], rest[comma:]
+		} else {
+			attribute.Value, rest = rest, ""
+		}
+		attributes = append(attributes, attribute)
+		list = strings.TrimPrefix(rest, ",")
+	}
+	return attributes
+}
+
+// attributeWriter builds an attribute list, skipping empty values
+type attributeWriter struct {
+	attributes []string
+}
+
+func (w *attributeWriter) quoted(key, value string) {
+	if value != "" {
+		w.attributes = append(w.attributes, key+`="`+value+`"`)
+	}
+}
+
+func (w *attributeWriter) enum(key, value string) {
+	if value != "" {
+		w.attributes = append(w.attributes, key+"="+value)
+	}
+}
+
+func (w *attributeWriter) int(key string, value int) {
+	if value > 0 {
+		w.attributes = append(w.attributes, key+"="+strconv.Itoa(value))
+	}
+}
+
+func (w *attributeWriter) float(key string, value float64) {
+	if value > 0 {
+		w.attributes = append(w.attributes, key+"="+strconv.FormatFloat(value, 'f', 3, 64))
+	}
+}
+
+func (w *attributeWriter) yes(key string, value bool) {
+	if value {
+		w.attributes = append(w.attributes, key+"=YES")
+	}
+}
+
+func (w *attributeWriter) extra(attributes []Attribute) {
+	for _, attribute := range attributes {
+		w.attributes = append(w.attributes, attribute.String())
+	}
+}
+
+func (w *attributeWriter) String() string {
+	return strings.Join(w.attributes, ",")
+}
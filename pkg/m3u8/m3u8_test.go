@@ -0,0 +1,164 @@
+package m3u8
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const master = `#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="Example"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",LANGUAGE="en",DEFAULT=YES,AUTOSELECT=YES,CHANNELS="2",URI="audio_en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=3080000,AVERAGE-BANDWIDTH=2928000,RESOLUTION=1280x720,FRAME-RATE=25.000,CODECS="avc1.64001f,mp4a.40.2",AUDIO="aac",HDCP-LEVEL=NONE
+720p.m3u8
+# mobile variant
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2",AUDIO="aac"
+360p.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=280000,RESOLUTION=1280x720,CODECS="avc1.64001f",URI="720p_iframes.m3u8"
+#EXT-X-CUSTOM-TRAILER
+`
+
+const media = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-ALLOW-CACHE:NO
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x00000000000000000000000000000001
+#EXT-X-MAP:URI="init.mp4",BYTERANGE="720@0"
+#EXT-X-PROGRAM-DATE-TIME:2024-01-02T03:04:05.000Z
+#EXTINF:4.000000,
+#EXT-X-BYTERANGE:1000@720
+stream.mp4
+#EXT-X-DISCONTINUITY
+#EXT-X-CUE-OUT:30
+#EXTINF:3.500000,ad
+#EXT-X-BYTERANGE:800
+stream.mp4
+#EXT-X-CUSTOM-TRAILER
+#EXT-X-ENDLIST
+`
+
+// ffmpegMedia is written by ffmpeg with -hls_flags program_date_time
+const ffmpegMedia = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PROGRAM-DATE-TIME:2024-01-02T03:04:05.000+0000
+#EXTINF:4.000000,
+stream0.ts
+#EXT-X-PROGRAM-DATE-TIME:2024-01-02T04:04:09.000+0100
+#EXTINF:4.000000,
+stream1.ts
+`
+
+func TestParseMaster(t *testing.T) {
+	t.Run("Should parse variants, renditions and keep unknown attributes", func(t *testing.T) {
+		playlist, err := Parse(strings.NewReader(master))
+		require.NoError(t, err)
+		p, ok := playlist.(*MasterPlaylist)
+		require.True(t, ok)
+
+		require.Equal(t, 4, p.Version)
+		require.True(t, p.IndependentSegments)
+		require.Equal(t, []string{`#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="Example"`}, p.Tags)
+		require.Equal(t, &Media{Type: "AUDIO", GroupID: "aac", Name: "English", Language: "en", Default: true, AutoSelect: true, Channels: "2", URI: "audio_en.m3u8"}, p.Media[0])
+
+		variant := p.Variants[0]
+		require.Equal(t, "720p.m3u8", variant.URI)
+		require.Equal(t, 3080000, variant.Bandwidth)
+		require.Equal(t, "avc1.64001f,mp4a.40.2", variant.Codecs)
+		require.Equal(t, []Attribute{{Key: "HDCP-LEVEL", Value: "NONE"}}, variant.Extra)
+		require.Equal(t, "720p_iframes.m3u8", p.IFrameVariants[0].URI)
+		require.Equal(t, []string{"# mobile variant"}, p.Variants[1].Tags)
+		require.Equal(t, []string{"#EXT-X-CUSTOM-TRAILER"}, p.Trailer)
+	})
+
+	t.Run("Should round-trip", func(t *testing.T) {
+		playlist, err := Parse(strings.NewReader(master))
+		require.NoError(t, err)
+		require.Equal(t, master, playlist.String())
+	})
+
+	t.Run("Should rewrite URIs", func(t *testing.T) {
+		playlist, err := Parse(strings.NewReader(master))
+		require.NoError(t, err)
+		p := playlist.(*MasterPlaylist)
+		p.RewriteURIs(func(uri string) string { return "https://cdn.example.com/" + uri })
+		require.Equal(t, "https://cdn.example.com/audio_en.m3u8", p.Media[0].URI)
+		require.Equal(t, "https://cdn.example.com/720p.m3u8", p.Variants[0].URI)
+		require.Equal(t, "https://cdn.example.com/720p_iframes.m3u8", p.IFrameVariants[0].URI)
+	})
+}
+
+func TestParseMedia(t *testing.T) {
+	t.Run("Should parse segments and their tags", func(t *testing.T) {
+		playlist, err := Parse(strings.NewReader(media))
+		require.NoError(t, err)
+		p, ok := playlist.(*MediaPlaylist)
+		require.True(t, ok)
+
+		require.Equal(t, 4, p.TargetDuration)
+		require.Equal(t, "VOD", p.PlaylistType)
+		require.True(t, p.EndList)
+		require.Equal(t, []string{"#EXT-X-ALLOW-CACHE:NO"}, p.Tags)
+		require.Equal(t, []string{"#EXT-X-CUSTOM-TRAILER"}, p.Trailer)
+		require.Len(t, p.Segments, 2)
+		require.Equal(t, 7500*time.Millisecond, p.Duration())
+
+		first := p.Segments[0]
+		require.Equal(t, &Key{Method: "AES-128", URI: "key.bin", IV: "0x00000000000000000000000000000001"}, first.Key)
+		require.Equal(t, &Map{URI: "init.mp4", ByteRange: &ByteRange{Length: 720, HasOffset: true}}, first.Map)
+		require.Equal(t, &ByteRange{Length: 1000, Offset: 720, HasOffset: true}, first.ByteRange)
+		require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), first.ProgramDateTime.UTC())
+
+		second := p.Segments[1]
+		require.True(t, second.Discontinuity)
+		require.Equal(t, "ad", second.Title)
+		require.Equal(t, []string{"#EXT-X-CUE-OUT:30"}, second.Tags)
+		require.Equal(t, &ByteRange{Length: 800}, second.ByteRange)
+	})
+
+	t.Run("Should round-trip", func(t *testing.T) {
+		playlist, err := Parse(strings.NewReader(media))
+		require.NoError(t, err)
+		require.Equal(t, media, playlist.String())
+	})
+
+	t.Run("Should parse the program date time written by ffmpeg", func(t *testing.T) {
+		playlist, err := Parse(strings.NewReader(ffmpegMedia))
+		require.NoError(t, err)
+		p := playlist.(*MediaPlaylist)
+		require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), p.Segments[0].ProgramDateTime.UTC())
+		require.Equal(t, time.Date(2024, 1, 2, 3, 4, 9, 0, time.UTC), p.Segments[1].ProgramDateTime.UTC())
+	})
+
+	t.Run("Should write inserted discontinuities", func(t *testing.T) {
+		p := &MediaPlaylist{TargetDuration: 4, Segments: []*Segment{{URI: "a.ts", Duration: 4}, {URI: "b.ts", Duration: 4, Discontinuity: true}}}
+		require.Equal(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:0\n#EXTINF:4.000000,\na.ts\n#EXT-X-DISCONTINUITY\n#EXTINF:4.000000,\nb.ts\n", p.String())
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Run("Should require the EXTM3U header", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("#EXTINF:4,\na.ts\n"))
+		require.ErrorIs(t, err, ErrNotPlaylist)
+	})
+
+	t.Run("Should reject a variant without URI", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("Should report the line number counting blank lines", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("#EXTM3U\n\n#EXT-X-STREAM-INF:BANDWIDTH=1\n720p.m3u8\n\n#EXT-X-STREAM-INF:BANDWIDTH=x\n"))
+		require.ErrorContains(t, err, "line 6: invalid #EXT-X-STREAM-INF")
+
+		_, err = Parse(strings.NewReader("#EXTM3U\n\n#EXT-X-TARGETDURATION:x\n"))
+		require.ErrorContains(t, err, "line 3: invalid #EXT-X-TARGETDURATION")
+	})
+}
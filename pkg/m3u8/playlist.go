@@ -0,0 +1,316 @@
+// Package m3u8 parses and writes HLS master and media playlists.
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Playlist is either a *MasterPlaylist or a *MediaPlaylist
+type Playlist interface {
+	io.WriterTo
+	String() string
+}
+
+// MasterPlaylist lists the variants and renditions of a presentation
+type MasterPlaylist struct {
+	Version             int
+	IndependentSegments bool
+	Media               []*Media
+	Variants            []*Variant
+	IFrameVariants      []*Variant
+	// Tags holds the unknown tags before the first rendition or variant, written
+	// back after the known header tags, Trailer the ones after the last variant
+	Tags    []string
+	Trailer []string
+}
+
+// Media is an EXT-X-MEDIA rendition
+type Media struct {
+	Type            string
+	GroupID         string
+	Name            string
+	Language        string
+	URI             string
+	Default         bool
+	AutoSelect      bool
+	Forced          bool
+	Channels        string
+	Characteristics string
+	InstreamID      string
+	Extra           []Attribute
+}
+
+// Variant is an EXT-X-STREAM-INF or an EXT-X-I-FRAME-STREAM-INF entry
+type Variant struct {
+	URI              string
+	Bandwidth        int
+	AverageBandwidth int
+	Codecs           string
+	Resolution       string
+	FrameRate        float64
+	Audio            string
+	Video            string
+	Subtitles        string
+	ClosedCaptions   string
+	Extra            []Attribute
+	Tags             []string // unknown tags and comments written right before the variant
+}
+
+// MediaPlaylist lists the segments of one rendition
+type MediaPlaylist struct {
+	Version               int
+	TargetDuration        int
+	MediaSequence         int
+	DiscontinuitySequence int
+	PlaylistType          string
+	IndependentSegments   bool
+	EndList               bool
+	Segments              []*Segment
+	// Tags holds unknown header tags, Trailer the unknown tags after the last segment
+	Tags    []string
+	Trailer []string
+}
+
+// Segment is a media segment and the tags written right before it. Key and Map
+// are only set on the segment they precede, and apply to the following ones.
+type Segment struct {
+	URI             string
+	Duration        float64
+	Title           string
+	ByteRange       *ByteRange
+	Key             *Key
+	Map             *Map
+	ProgramDateTime time.Time
+	Discontinuity   bool
+	Tags            []string
+}
+
+// ByteRange is an EXT-X-BYTERANGE sub-range, Offset is ignored unless HasOffset
+type ByteRange struct {
+	Length    int64
+	Offset    int64
+	HasOffset bool
+}
+
+// Key is an EXT-X-KEY encryption method
+type Key struct {
+	Method            string
+	URI               string
+	IV                string
+	KeyFormat         string
+	KeyFormatVersions string
+	Extra             []Attribute
+}
+
+// Map is an EXT-X-MAP media initialization section
+type Map struct {
+	URI       string
+	ByteRange *ByteRange
+	Extra     []Attribute
+}
+
+// ProgramDateTimeLayout is the layout EXT-X-PROGRAM-DATE-TIME is written with
+const ProgramDateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Duration returns the summed duration of the segments
+func (p *MediaPlaylist) Duration() time.Duration {
+	var total float64
+	for _, segment := range p.Segments {
+		total += segment.Duration
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+// RewriteURIs replaces the URI of every segment, key and map with rewrite(uri)
+func (p *MediaPlaylist) RewriteURIs(rewrite func(string) string) {
+	for _, segment := range p.Segments {
+		segment.URI = rewrite(segment.URI)
+		if segment.Key != nil && segment.Key.URI != "" {
+			segment.Key.URI = rewrite(segment.Key.URI)
+		}
+		if segment.Map != nil {
+			segment.Map.URI = rewrite(segment.Map.URI)
+		}
+	}
+}
+
+// RewriteURIs replaces the URI of every variant and rendition with rewrite(uri)
+func (p *MasterPlaylist) RewriteURIs(rewrite func(string) string) {
+	for _, media := range p.Media {
+		if media.URI != "" {
+			media.URI = rewrite(media.URI)
+		}
+	}
+	for _, variant := range append(p.Variants, p.IFrameVariants...) {
+		variant.URI = rewrite(variant.URI)
+	}
+}
+
+// WriteTo writes the playlist to w
+func (p *MasterPlaylist) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	if p.Version > 0 {
+		fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", p.Version)
+	}
+	if p.IndependentSegments {
+		b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	}
+	writeTags(&b, p.Tags)
+
+	for _, media := range p.Media {
+		attributes := attributeWriter{}
+		attributes.enum("TYPE", media.Type)
+		attributes.quoted("GROUP-ID", media.GroupID)
+		attributes.quoted("NAME", media.Name)
+		attributes.quoted("LANGUAGE", media.Language)
+		attributes.yes("DEFAULT", media.Default)
+		attributes.yes("AUTOSELECT", media.AutoSelect)
+		attributes.yes("FORCED", media.Forced)
+		attributes.quoted("INSTREAM-ID", media.InstreamID)
+		attributes.quoted("CHARACTERISTICS", media.Characteristics)
+		attributes.quoted("CHANNELS", media.Channels)
+		attributes.quoted("URI", media.URI)
+		attributes.extra(media.Extra)
+		fmt.Fprintf(&b, "#EXT-X-MEDIA:%s\n", attributes.String())
+	}
+	for _, variant := range p.Variants {
+		writeTags(&b, variant.Tags)
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:%s\n%s\n", variant.attributes(false), variant.URI)
+	}
+	for _, variant := range p.IFrameVariants {
+		writeTags(&b, variant.Tags)
+		fmt.Fprintf(&b, "#EXT-X-I-FRAME-STREAM-INF:%s\n", variant.attributes(true))
+	}
+	writeTags(&b, p.Trailer)
+	return b.WriteTo(w)
+}
+
+func (v *Variant) attributes(iframe bool) string {
+	attributes := attributeWriter{}
+	attributes.int("BANDWIDTH", v.Bandwidth)
+	attributes.int("AVERAGE-BANDWIDTH", v.AverageBandwidth)
+	attributes.enum("RESOLUTION", v.Resolution)
+	if !iframe {
+		attributes.float("FRAME-RATE", v.FrameRate)
+	}
+	attributes.quoted("CODECS", v.Codecs)
+	attributes.quoted("VIDEO", v.Video)
+	if !iframe {
+		attributes.quoted("AUDIO", v.Audio)
+		attributes.quoted("SUBTITLES", v.Subtitles)
+		if v.ClosedCaptions == "NONE" {
+			attributes.enum("CLOSED-CAPTIONS", v.ClosedCaptions)
+		} else {
+			attributes.quoted("CLOSED-CAPTIONS", v.ClosedCaptions)
+		}
+	}
+	attributes.extra(v.Extra)
+	if iframe {
+		attributes.quoted("URI", v.URI)
+	}
+	return attributes.String()
+}
+
+// WriteTo writes the playlist to w
+func (p *MediaPlaylist) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	if p.Version > 0 {
+		fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", p.Version)
+	}
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", p.TargetDuration)
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", p.MediaSequence)
+	if p.DiscontinuitySequence > 0 {
+		fmt.Fprintf(&b, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", p.DiscontinuitySequence)
+	}
+	if p.PlaylistType != "" {
+		fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", p.PlaylistType)
+	}
+	if p.IndependentSegments {
+		b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	}
+	writeTags(&b, p.Tags)
+
+	for _, segment := range p.Segments {
+		if segment.Discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if segment.Key != nil {
+			fmt.Fprintf(&b, "#EXT-X-KEY:%s\n", segment.Key.attributes())
+		}
+		if segment.Map != nil {
+			attributes := attributeWriter{}
+			attributes.quoted("URI", segment.Map.URI)
+			if segment.Map.ByteRange != nil {
+				attributes.quoted("BYTERANGE", segment.Map.ByteRange.String())
+			}
+			attributes.extra(segment.Map.Extra)
+			fmt.Fprintf(&b, "#EXT-X-MAP:%s\n", attributes.String())
+		}
+		if !segment.ProgramDateTime.IsZero() {
+			fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.ProgramDateTime.Format(ProgramDateTimeLayout))
+		}
+		writeTags(&b, segment.Tags)
+		fmt.Fprintf(&b, "#EXTINF:%s,%s\n", strconv.FormatFloat(segment.Duration, 'f', 6, 64), segment.Title)
+		if segment.ByteRange != nil {
+			fmt.Fprintf(&b, "#EXT-X-BYTERANGE:%s\n", segment.ByteRange.String())
+		}
+		b.WriteString(segment.URI + "\n")
+	}
+
+	writeTags(&b, p.Trailer)
+	if p.EndList {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.WriteTo(w)
+}
+
+func (k *Key) attributes() string {
+	attributes := attributeWriter{}
+	attributes.enum("METHOD", k.Method)
+	attributes.quoted("URI", k.URI)
+	attributes.enum("IV", k.IV)
+	attributes.quoted("KEYFORMAT", k.KeyFormat)
+	attributes.quoted("KEYFORMATVERSIONS", k.KeyFormatVersions)
+	attributes.extra(k.Extra)
+	return attributes.String()
+}
+
+func (r *ByteRange) String() string {
+	if r.HasOffset {
+		return fmt.Sprintf("%d@%d", r.Length, r.Offset)
+	}
+	return strconv.FormatInt(r.Length, 10)
+}
+
+func (p *MasterPlaylist) String() string {
+	return playlistString(p)
+}
+
+func (p *MediaPlaylist) String() string {
+	return playlistString(p)
+}
+
+func playlistString(p io.WriterTo) string {
+	var b strings.Builder
+	p.WriteTo(&b)
+	return b.String()
+}
+
+func writeTags(b *bytes.Buffer, tags []string) {
+	for _, tag := range tags {
+		b.WriteString(tag + "\n")
+	}
+}
+
+// WriteFile writes the playlist to path
+func WriteFile(path string, p Playlist) error {
+	return os.WriteFile(path, []byte(p.String()), 0644)
+}
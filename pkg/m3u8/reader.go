@@ -0,0 +1,344 @@
+package m3u8
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotPlaylist is returned when the content does not start with #EXTM3U
+var ErrNotPlaylist = errors.New("m3u8: missing #EXTM3U header")
+
+// line is a non-blank playlist line and its number in the content
+type line struct {
+	n    int
+	text string
+}
+
+// Parse reads a master or a media playlist. Unknown tags and comments are kept
+// so the playlist can be written back without losing them.
+func Parse(r io.Reader) (Playlist, error) {
+	var lines []line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if text := strings.TrimSpace(scanner.Text()); text != "" {
+			lines = append(lines, line{n: n, text: text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || strings.TrimPrefix(lines[0].text, "\ufeff") != "#EXTM3U" {
+		return nil, ErrNotPlaylist
+	}
+	lines = lines[1:]
+
+	if isMaster(lines) {
+		master, err := parseMasterPlaylist(lines)
+		if err != nil {
+			return nil, err
+		}
+		return master, nil
+	}
+	media, err := parseMediaPlaylist(lines)
+	if err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// ParseFile reads the playlist at path
+func ParseFile(path string) (Playlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+func isMaster(lines []line) bool {
+	for _, line := range lines {
+		tag, _ := splitTag(line.text)
+		switch tag {
+		case "#EXT-X-STREAM-INF", "#EXT-X-I-FRAME-STREAM-INF", "#EXT-X-MEDIA":
+			return true
+		case "#EXTINF", "#EXT-X-TARGETDURATION":
+			return false
+		}
+	}
+	return false
+}
+
+func splitTag(line string) (string, string) {
+	tag, value, _ := strings.Cut(line, ":")
+	return tag, value
+}
+
+func parseMasterPlaylist(lines []line) (*MasterPlaylist, error) {
+	p := new(MasterPlaylist)
+	var pending *Variant
+	var tags []string
+	started := false
+	for _, line := range lines {
+		if !strings.HasPrefix(line.text, "#") {
+			if pending == nil {
+				return nil, fmt.Errorf("m3u8: line %d: URI %q without EXT-X-STREAM-INF", line.n, line.text)
+			}
+			pending.URI = line.text
+			p.Variants = append(p.Variants, pending)
+			pending = nil
+			continue
+		}
+
+		tag, value := splitTag(line.text)
+		var err error
+		switch tag {
+		case "#EXT-X-VERSION":
+			p.Version, err = strconv.Atoi(value)
+		case "#EXT-X-INDEPENDENT-SEGMENTS":
+			p.IndependentSegments = true
+		case "#EXT-X-MEDIA":
+			p.Media = append(p.Media, parseMediaTag(value))
+			started = true
+		case "#EXT-X-STREAM-INF":
+			if pending, err = parseVariant(value); err == nil {
+				pending.Tags, tags = tags, nil
+			}
+			started = true
+		case "#EXT-X-I-FRAME-STREAM-INF":
+			var variant *Variant
+			if variant, err = parseVariant(value); err == nil {
+				variant.Tags, tags = tags, nil
+				p.IFrameVariants = append(p.IFrameVariants, variant)
+			}
+			started = true
+		default:
+			if started {
+				tags = append(tags, line.text)
+			} else {
+				p.Tags = append(p.Tags, line.text)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("m3u8: line %d: invalid %s: %w", line.n, tag, err)
+		}
+	}
+	if pending != nil {
+		return nil, errors.New("m3u8: EXT-X-STREAM-INF without URI")
+	}
+
+	// Tags left after the last variant belong to the playlist itself
+	p.Trailer = tags
+	return p, nil
+}
+
+func parseMediaTag(value string) *Media {
+	media := new(Media)
+	for _, attribute := range parseAttributes(value) {
+		switch attribute.Key {
+		case "TYPE":
+			media.Type = attribute.Value
+		case "GROUP-ID":
+			media.GroupID = attribute.Value
+		case "NAME":
+			media.Name = attribute.Value
+		case "LANGUAGE":
+			media.Language = attribute.Value
+		case "URI":
+			media.URI = attribute.Value
+		case "DEFAULT":
+			media.Default = attribute.Value == "YES"
+		case "AUTOSELECT":
+			media.AutoSelect = attribute.Value == "YES"
+		case "FORCED":
+			media.Forced = attribute.Value == "YES"
+		case "CHANNELS":
+			media.Channels = attribute.Value
+		case "CHARACTERISTICS":
+			media.Characteristics = attribute.Value
+		case "INSTREAM-ID":
+			media.InstreamID = attribute.Value
+		default:
+			media.Extra = append(media.Extra, attribute)
+		}
+	}
+	return media
+}
+
+func parseVariant(value string) (*Variant, error) {
+	variant := new(Variant)
+	var err error
+	for _, attribute := range parseAttributes(value) {
+		switch attribute.Key {
+		case "BANDWIDTH":
+			variant.Bandwidth, err = strconv.Atoi(attribute.Value)
+		case "AVERAGE-BANDWIDTH":
+			variant.AverageBandwidth, err = strconv.Atoi(attribute.Value)
+		case "CODECS":
+			variant.Codecs = attribute.Value
+		case "RESOLUTION":
+			variant.Resolution = attribute.Value
+		case "FRAME-RATE":
+			variant.FrameRate, err = strconv.ParseFloat(attribute.Value, 64)
+		case "AUDIO":
+			variant.Audio = attribute.Value
+		case "VIDEO":
+			variant.Video = attribute.Value
+		case "SUBTITLES":
+			variant.Subtitles = attribute.Value
+		case "CLOSED-CAPTIONS":
+			variant.ClosedCaptions = attribute.Value
+		case "URI":
+			variant.URI = attribute.Value
+		default:
+			variant.Extra = append(variant.Extra, attribute)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", attribute.Key, err)
+		}
+	}
+	return variant, nil
+}
+
+func parseMediaPlaylist(lines []line) (*MediaPlaylist, error) {
+	p := new(MediaPlaylist)
+	segment := new(Segment)
+	started := false
+	for _, line := range lines {
+		if !strings.HasPrefix(line.text, "#") {
+			segment.URI = line.text
+			p.Segments = append(p.Segments, segment)
+			segment = new(Segment)
+			continue
+		}
+
+		tag, value := splitTag(line.text)
+		var err error
+		switch tag {
+		case "#EXT-X-VERSION":
+			p.Version, err = strconv.Atoi(value)
+		case "#EXT-X-TARGETDURATION":
+			p.TargetDuration, err = strconv.Atoi(value)
+		case "#EXT-X-MEDIA-SEQUENCE":
+			p.MediaSequence, err = strconv.Atoi(value)
+		case "#EXT-X-DISCONTINUITY-SEQUENCE":
+			p.DiscontinuitySequence, err = strconv.Atoi(value)
+		case "#EXT-X-PLAYLIST-TYPE":
+			p.PlaylistType = value
+		case "#EXT-X-INDEPENDENT-SEGMENTS":
+			p.IndependentSegments = true
+		case "#EXT-X-ENDLIST":
+			p.EndList = true
+		case "#EXTINF":
+			duration, title, _ := strings.Cut(value, ",")
+			segment.Title = title
+			segment.Duration, err = strconv.ParseFloat(duration, 64)
+			started = true
+		case "#EXT-X-BYTERANGE":
+			segment.ByteRange, err = parseByteRange(value)
+			started = true
+		case "#EXT-X-DISCONTINUITY":
+			segment.Discontinuity = true
+			started = true
+		case "#EXT-X-KEY":
+			segment.Key = parseKey(value)
+			started = true
+		case "#EXT-X-MAP":
+			segment.Map, err = parseMap(value)
+			started = true
+		case "#EXT-X-PROGRAM-DATE-TIME":
+			segment.ProgramDateTime, err = parseProgramDateTime(value)
+			started = true
+		default:
+			if started {
+				segment.Tags = append(segment.Tags, line.text)
+			} else {
+				p.Tags = append(p.Tags, line.text)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("m3u8: line %d: invalid %s: %w", line.n, tag, err)
+		}
+	}
+
+	// Tags left after the last segment belong to the playlist itself
+	p.Trailer = segment.Tags
+	return p, nil
+}
+
+func parseByteRange(value string) (*ByteRange, error) {
+	length, offset, hasOffset := strings.Cut(value, "@")
+	r := &ByteRange{HasOffset: hasOffset}
+	var err error
+	if r.Length, err = strconv.ParseInt(length, 10, 64); err != nil {
+		return nil, err
+	}
+	if hasOffset {
+		if r.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func parseKey(value string) *Key {
+	key := new(Key)
+	for _, attribute := range parseAttributes(value) {
+		switch attribute.Key {
+		case "METHOD":
+			key.Method = attribute.Value
+		case "URI":
+			key.URI = attribute.Value
+		case "IV":
+			key.IV = attribute.Value
+		case "KEYFORMAT":
+			key.KeyFormat = attribute.Value
+		case "KEYFORMATVERSIONS":
+			key.KeyFormatVersions = attribute.Value
+		default:
+			key.Extra = append(key.Extra, attribute)
+		}
+	}
+	return key
+}
+
+func parseMap(value string) (*Map, error) {
+	m := new(Map)
+	for _, attribute := range parseAttributes(value) {
+		switch attribute.Key {
+		case "URI":
+			m.URI = attribute.Value
+		case "BYTERANGE":
+			r, err := parseByteRange(attribute.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.ByteRange = r
+		default:
+			m.Extra = append(m.Extra, attribute)
+		}
+	}
+	return m, nil
+}
+
+// programDateTimeOffsetLayout is the layout ffmpeg writes EXT-X-PROGRAM-DATE-TIME
+// with, whose strftime %z offset has no colon
+const programDateTimeOffsetLayout = "2006-01-02T15:04:05.999999999-0700"
+
+func parseProgramDateTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return t, nil
+	}
+	if t, offsetErr := time.Parse(programDateTimeOffsetLayout, value); offsetErr == nil {
+		return t, nil
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,191 @@
+// Package mpd parses MPEG-DASH manifests.
+package mpd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MPD is a Media Presentation Description
+type MPD struct {
+	XMLName                   xml.Name `xml:"MPD"`
+	Type                      string   `xml:"type,attr"`
+	Profiles                  string   `xml:"profiles,attr"`
+	MediaPresentationDuration Duration `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             Duration `xml:"minBufferTime,attr"`
+	MaxSegmentDuration        Duration `xml:"maxSegmentDuration,attr"`
+	Periods                   []Period `xml:"Period"`
+}
+
+// Period is a time span of the presentation
+type Period struct {
+	ID             string          `xml:"id,attr"`
+	Start          Duration        `xml:"start,attr"`
+	AdaptationSets []AdaptationSet `xml:"AdaptationSet"`
+}
+
+// AdaptationSet is a set of interchangeable representations
+type AdaptationSet struct {
+	ID               string           `xml:"id,attr"`
+	ContentType      string           `xml:"contentType,attr"`
+	Lang             string           `xml:"lang,attr"`
+	MimeType         string           `xml:"mimeType,attr"`
+	SegmentAlignment string           `xml:"segmentAlignment,attr"`
+	SegmentTemplate  *SegmentTemplate `xml:"SegmentTemplate"`
+	Representations  []Representation `xml:"Representation"`
+}
+
+// Representation is one encoding of the content
+type Representation struct {
+	ID                string           `xml:"id,attr"`
+	MimeType          string           `xml:"mimeType,attr"`
+	Codecs            string           `xml:"codecs,attr"`
+	Bandwidth         int              `xml:"bandwidth,attr"`
+	Width             int              `xml:"width,attr"`
+	Height            int              `xml:"height,attr"`
+	FrameRate         string           `xml:"frameRate,attr"`
+	AudioSamplingRate string           `xml:"audioSamplingRate,attr"`
+	SegmentTemplate   *SegmentTemplate `xml:"SegmentTemplate"`
+}
+
+// SegmentTemplate describes the segment URLs of a representation
+type SegmentTemplate struct {
+	Timescale       int              `xml:"timescale,attr"`
+	Duration        int64            `xml:"duration,attr"`
+	StartNumber     int              `xml:"startNumber,attr"`
+	Initialization  string           `xml:"initialization,attr"`
+	Media           string           `xml:"media,attr"`
+	SegmentTimeline *SegmentTimeline `xml:"SegmentTimeline"`
+}
+
+// SegmentTimeline lists the segments of a template
+type SegmentTimeline struct {
+	S []S `xml:"S"`
+}
+
+// S is a run of R+1 segments of duration D starting at T
+type S struct {
+	T int64 `xml:"t,attr"`
+	D int64 `xml:"d,attr"`
+	R int   `xml:"r,attr"`
+}
+
+// Parse reads an MPD
+func Parse(r io.Reader) (*MPD, error) {
+	manifest := new(MPD)
+	if err := xml.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("invalid MPD: %w", err)
+	}
+	return manifest, nil
+}
+
+// ParseFile reads the MPD at path
+func ParseFile(path string) (*MPD, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Representations returns every representation of every period
+func (m *MPD) Representations() []Representation {
+	var representations []Representation
+	for _, period := range m.Periods {
+		for _, set := range period.AdaptationSets {
+			representations = append(representations, set.Representations...)
+		}
+	}
+	return representations
+}
+
+// SegmentCount returns the number of segments listed by the timeline
+func (t *SegmentTimeline) SegmentCount() int {
+	count := 0
+	for _, n := range t.repeats() {
+		count += n
+	}
+	return count
+}
+
+// repeats returns the number of segments of each S. A run repeated until the
+// next S (R = -1) ends at the start time of the next one; the last run of a
+// timeline counts a single segment then, as the period end is not known here.
+func (t *SegmentTimeline) repeats() []int {
+	counts := make([]int, len(t.S))
+	var start int64
+	for i, s := range t.S {
+		if s.T > 0 {
+			start = s.T
+		}
+		counts[i] = s.R + 1
+		if s.R < 0 {
+			counts[i] = 1
+			if i+1 < len(t.S) && t.S[i+1].T > start && s.D > 0 {
+				counts[i] = int((t.S[i+1].T - start + s.D - 1) / s.D)
+			}
+		}
+		start += s.D * int64(counts[i])
+	}
+	return counts
+}
+
+// TotalDuration returns the summed duration of the timeline segments
+func (t *SegmentTemplate) TotalDuration() time.Duration {
+	if t.SegmentTimeline == nil || t.Timescale == 0 {
+		return 0
+	}
+	var units int64
+	for i, n := range t.SegmentTimeline.repeats() {
+		units += t.SegmentTimeline.S[i].D * int64(n)
+	}
+	// Scale the whole seconds and the remainder apart, units * time.Second overflows
+	timescale := int64(t.Timescale)
+	return time.Duration(units/timescale)*time.Second + time.Duration(units%timescale)*time.Second/time.Duration(timescale)
+}
+
+// Duration is an ISO 8601 duration attribute, e.g. "PT1M30.5S"
+type Duration time.Duration
+
+var durationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses an ISO 8601 duration without years or months
+func ParseDuration(value string) (time.Duration, error) {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(v * float64(unit))
+	}
+	return d, nil
+}
+
+func (d *Duration) UnmarshalXMLAttr(attr xml.Attr) error {
+	parsed, err := ParseDuration(attr.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Duration returns the value as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
@@ -0,0 +1,75 @@
+package mpd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const manifest = `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="static"
+	mediaPresentationDuration="PT40.0S" maxSegmentDuration="PT4.0S" minBufferTime="PT8.0S">
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video" segmentAlignment="true">
+			<Representation id="0" mimeType="video/mp4" codecs="avc1.64001f" bandwidth="2800000" width="1280" height="720" frameRate="25/1">
+				<SegmentTemplate timescale="12800" initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s" startNumber="1">
+					<SegmentTimeline>
+						<S t="0" d="51200" r="9" />
+					</SegmentTimeline>
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+		<AdaptationSet id="1" contentType="audio" lang="eng">
+			<Representation id="1" mimeType="audio/mp4" codecs="mp4a.40.2" bandwidth="128000" audioSamplingRate="48000" />
+		</AdaptationSet>
+	</Period>
+</MPD>`
+
+func TestParse(t *testing.T) {
+	t.Run("Should parse periods, adaptation sets and representations", func(t *testing.T) {
+		m, err := Parse(strings.NewReader(manifest))
+		require.NoError(t, err)
+		require.Equal(t, "static", m.Type)
+		require.Equal(t, 40*time.Second, m.MediaPresentationDuration.Duration())
+		require.Len(t, m.Periods[0].AdaptationSets, 2)
+		require.Equal(t, "eng", m.Periods[0].AdaptationSets[1].Lang)
+
+		representations := m.Representations()
+		require.Len(t, representations, 2)
+		require.Equal(t, 1280, representations[0].Width)
+		require.Equal(t, "avc1.64001f", representations[0].Codecs)
+
+		template := representations[0].SegmentTemplate
+		require.Equal(t, 10, template.SegmentTimeline.SegmentCount())
+		require.Equal(t, 40*time.Second, template.TotalDuration())
+	})
+
+	t.Run("Should sum long timelines and runs repeated until the next one", func(t *testing.T) {
+		template := SegmentTemplate{Timescale: 1000000, SegmentTimeline: &SegmentTimeline{S: []S{
+			{T: 0, D: 4000000, R: -1},
+			{T: 10800000000, D: 2000000, R: 1},
+		}}}
+		require.Equal(t, 2702, template.SegmentTimeline.SegmentCount())
+		require.Equal(t, 3*time.Hour+4*time.Second, template.TotalDuration())
+	})
+
+	t.Run("Should reject invalid manifests", func(t *testing.T) {
+		_, err := Parse(strings.NewReader(`<MPD mediaPresentationDuration="40s"></MPD>`))
+		require.Error(t, err)
+	})
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"PT40.5S":    40*time.Second + 500*time.Millisecond,
+		"PT1H2M3S":   time.Hour + 2*time.Minute + 3*time.Second,
+		"P1DT0.001S": 24*time.Hour + time.Millisecond,
+	}
+	for value, expected := range cases {
+		d, err := ParseDuration(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, d, value)
+	}
+}
@@ -0,0 +1,297 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/media"
+	"github.com/graux/goffmpeg/pkg/filtergraph"
+)
+
+// ConcatMode is the way the items of a concatenation are joined
+type ConcatMode string
+
+const (
+	// ConcatModeAuto uses the demuxer when every item has the same codec
+	// parameters, and the filter otherwise
+	ConcatModeAuto ConcatMode = ""
+	// ConcatModeDemuxer joins the items without re-encoding through a list file
+	ConcatModeDemuxer ConcatMode = "demuxer"
+	// ConcatModeProtocol joins the raw bytes of the items, e.g. MPEG-TS segments
+	ConcatModeProtocol ConcatMode = "protocol"
+	// ConcatModeFilter decodes and re-encodes the items with the concat filter
+	ConcatModeFilter ConcatMode = "filter"
+)
+
+// ConcatItem is an input of a concatenation. A zero OutPoint means the end of the input.
+type ConcatItem struct {
+	Path     string
+	InPoint  time.Duration
+	OutPoint time.Duration
+	Metadata *media.Metadata
+}
+
+// NewConcat returns a Transcoder writing the concatenation of items to outputPath
+func NewConcat(items []ConcatItem, outputPath string, mode ConcatMode) (*Transcoder, error) {
+	tr := new(Transcoder)
+	if err := tr.InitializeConcat(items, outputPath, mode); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// InitializeConcat Init a concatenation of items. Items without Metadata are probed,
+// and progress is computed against the summed duration of the items.
+func (t *Transcoder) InitializeConcat(items []ConcatItem, outputPath string, mode ConcatMode) error {
+	var err error
+	cfg := t.configuration
+	if len(cfg.FFmpegBinPath()) == 0 || len(cfg.FFprobeBinPath()) == 0 {
+		cfg, err = goffmpeg.Configure(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(items) == 0 {
+		return errors.New("error on transcoder.InitializeConcat: items missing")
+	}
+
+	for i := range items {
+		if items[i].Path == "" {
+			return fmt.Errorf("error on transcoder.InitializeConcat: path of item %d missing", i)
+		}
+		if items[i].Metadata == nil {
+			if items[i].Metadata, err = media.NewMetadata(cfg, items[i].Path, t.whiteListProtocols...); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.resetHooks()
+	var MediaFile *media.File
+	switch resolveConcatMode(items, mode) {
+	case ConcatModeDemuxer:
+		MediaFile, err = t.concatDemuxerFile(items)
+	case ConcatModeProtocol:
+		MediaFile, err = concatProtocolFile(items)
+	case ConcatModeFilter:
+		MediaFile, err = concatFilterFile(items)
+	default:
+		err = fmt.Errorf("error on transcoder.InitializeConcat: unknown mode %q", mode)
+	}
+	if err != nil {
+		return err
+	}
+	MediaFile.SetOutputPath(outputPath)
+
+	// Set transcoder configuration
+	t.SetMediaFile(MediaFile)
+	t.SetConfiguration(cfg)
+	t.SetProgressDuration(concatDuration(items))
+
+	return nil
+}
+
+func resolveConcatMode(items []ConcatItem, mode ConcatMode) ConcatMode {
+	if mode != ConcatModeAuto {
+		return mode
+	}
+	for _, item := range items[1:] {
+		if !items[0].Metadata.CodecParametersMatch(*item.Metadata) {
+			return ConcatModeFilter
+		}
+	}
+	return ConcatModeDemuxer
+}
+
+func concatDuration(items []ConcatItem) time.Duration {
+	var total time.Duration
+	for _, item := range items {
+		end := item.OutPoint
+		if end == 0 {
+			end = item.Metadata.Format.Duration
+		}
+		if end > item.InPoint {
+			total += end - item.InPoint
+		}
+	}
+	return total
+}
+
+// concatDemuxerFile reads the items through a list file, written when a job
+// starts and removed once it has finished
+func (t *Transcoder) concatDemuxerFile(items []ConcatItem) (*media.File, error) {
+	file := new(media.File)
+	file.SetMetadata(items[0].Metadata)
+	file.SetRawInputArgs([]string{"-f", "concat", "-safe", "0"})
+	file.SetRawOutputArgs([]string{"-c", "copy"})
+
+	t.addSetup(func() error {
+		listPath, err := writeConcatList(items)
+		if err != nil {
+			return err
+		}
+		t.addCleanup(func() {
+			os.Remove(listPath)
+		})
+		file.SetInputPath(listPath)
+		return nil
+	})
+	return file, nil
+}
+
+// writeConcatList writes the ffconcat list of items to a temporary file
+func writeConcatList(items []ConcatItem) (string, error) {
+	list, err := os.CreateTemp("", "goffmpeg-concat-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer list.Close()
+
+	if _, err := list.WriteString(concatList(items)); err != nil {
+		os.Remove(list.Name())
+		return "", err
+	}
+	return list.Name(), nil
+}
+
+// concatList returns the ffconcat script of items. Local paths are made absolute
+// because the demuxer resolves them relative to the list file.
+func concatList(items []ConcatItem) string {
+	var list strings.Builder
+	list.WriteString("ffconcat version 1.0\n")
+	for _, item := range items {
+		path := item.Path
+		if isLocalFile(path) {
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+		}
+		list.WriteString("file " + quoteConcatPath(path) + "\n")
+		if item.InPoint > 0 {
+			list.WriteString("inpoint " + formatSeconds(item.InPoint) + "\n")
+		}
+		if item.OutPoint > 0 {
+			list.WriteString("outpoint " + formatSeconds(item.OutPoint) + "\n")
+		}
+	}
+	return list.String()
+}
+
+// quoteConcatPath quotes a path for an ffconcat script, closing the quotes
+// around every escaped single quote
+func quoteConcatPath(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+func concatProtocolFile(items []ConcatItem) (*media.File, error) {
+	paths := make([]string, len(items))
+	for i, item := range items {
+		if item.InPoint > 0 || item.OutPoint > 0 {
+			return nil, errors.New("error on transcoder.InitializeConcat: the protocol mode does not support inpoint or outpoint")
+		}
+		paths[i] = item.Path
+	}
+
+	file := new(media.File)
+	file.SetMetadata(items[0].Metadata)
+	file.SetInputPath("concat:" + strings.Join(paths, "|"))
+	file.SetRawOutputArgs([]string{"-c", "copy"})
+	return file, nil
+}
+
+func concatFilterFile(items []ConcatItem) (*media.File, error) {
+	graph, err := concatGraph(items)
+	if err != nil {
+		return nil, err
+	}
+
+	file := new(media.File)
+	file.SetMetadata(new(media.Metadata))
+	for _, item := range items {
+		input := &media.Input{Path: item.Path, Metadata: item.Metadata}
+		if item.InPoint > 0 {
+			input.SeekTime = formatSeconds(item.InPoint)
+		}
+		if item.OutPoint > item.InPoint {
+			input.Duration = formatSeconds(item.OutPoint - item.InPoint)
+		}
+		file.AddInput(input)
+	}
+	file.SetFilterGraph(graph)
+	return file, nil
+}
+
+// concatGraph normalizes every item to the format of the first one and joins them
+// with the concat filter. Audio or video is only kept when every item has it.
+func concatGraph(items []ConcatItem) (*filtergraph.Graph, error) {
+	first := items[0].Metadata
+	video, audio := first.FirstVideoStream(), first.FirstAudioStream()
+	for _, item := range items[1:] {
+		if item.Metadata.FirstVideoStream() == nil {
+			video = nil
+		}
+		if item.Metadata.FirstAudioStream() == nil {
+			audio = nil
+		}
+	}
+	if video == nil && audio == nil {
+		return nil, errors.New("error on transcoder.InitializeConcat: items share no audio or video stream")
+	}
+
+	g := filtergraph.New()
+	var pads []*filtergraph.Pad
+	for i := range items {
+		if video != nil {
+			width, height := strconv.Itoa(video.Width), strconv.Itoa(video.Height)
+			pads = append(pads, g.Chain([]*filtergraph.Pad{g.Input(fmt.Sprintf("%d:v:0", i))},
+				filtergraph.F("scale", width, height).Set("force_original_aspect_ratio", "decrease"),
+				filtergraph.F("pad", width, height, "(ow-iw)/2", "(oh-ih)/2"),
+				filtergraph.F("setsar", "1"),
+			).Out())
+		}
+		if audio != nil {
+			format := filtergraph.F("aformat")
+			if audio.SampleRate != "" {
+				format = format.Set("sample_rates", audio.SampleRate)
+			}
+			if audio.ChannelLayout != "" {
+				format = format.Set("channel_layouts", audio.ChannelLayout)
+			}
+			if len(format.Options) == 0 {
+				format = filtergraph.F("anull")
+			}
+			pads = append(pads, g.Chain([]*filtergraph.Pad{g.Input(fmt.Sprintf("%d:a:0", i))}, format).Out())
+		}
+	}
+
+	concat := g.Chain(pads, filtergraph.F("concat").
+		Set("n", strconv.Itoa(len(items))).
+		Set("v", boolFlag(video != nil)).
+		Set("a", boolFlag(audio != nil)))
+	if video != nil {
+		g.Output(concat.NamedOut("v"))
+	}
+	if audio != nil {
+		g.Output(concat.NamedOut("a"))
+	}
+	return g, nil
+}
+
+func boolFlag(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,120 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/graux/goffmpeg/media"
+	"github.com/stretchr/testify/require"
+)
+
+func concatMetadata(width int, duration time.Duration) *media.Metadata {
+	return &media.Metadata{
+		Format: media.Format{Duration: duration},
+		Streams: []media.Stream{
+			{Index: 0, CodecType: media.CodecTypeVideo, CodecName: "h264", Width: width, Height: 720},
+			{Index: 1, CodecType: media.CodecTypeAudio, CodecName: "aac", SampleRate: "48000", ChannelLayout: "stereo"},
+		},
+	}
+}
+
+func TestConcat(t *testing.T) {
+	t.Run("Should use the demuxer only when the codec parameters match", func(t *testing.T) {
+		same := []ConcatItem{{Metadata: concatMetadata(1280, time.Second)}, {Metadata: concatMetadata(1280, time.Second)}}
+		require.Equal(t, ConcatModeDemuxer, resolveConcatMode(same, ConcatModeAuto))
+
+		different := []ConcatItem{{Metadata: concatMetadata(1280, time.Second)}, {Metadata: concatMetadata(960, time.Second)}}
+		require.Equal(t, ConcatModeFilter, resolveConcatMode(different, ConcatModeAuto))
+		require.Equal(t, ConcatModeProtocol, resolveConcatMode(different, ConcatModeProtocol))
+	})
+
+	t.Run("Should escape the demuxer list and keep inpoint and outpoint", func(t *testing.T) {
+		list := concatList([]ConcatItem{
+			{Path: "/videos/it's.mp4", InPoint: 1500 * time.Millisecond, OutPoint: 10 * time.Second},
+			{Path: "https://cdn/b.mp4"},
+		})
+		require.Equal(t, "ffconcat version 1.0\nfile '/videos/it'\\''s.mp4'\ninpoint 1.5\noutpoint 10\nfile 'https://cdn/b.mp4'\n", list)
+	})
+
+	t.Run("Should sum the duration of the items", func(t *testing.T) {
+		items := []ConcatItem{
+			{Metadata: concatMetadata(1280, 30*time.Second), InPoint: 10 * time.Second},
+			{Metadata: concatMetadata(1280, 30*time.Second), OutPoint: 5 * time.Second},
+		}
+		require.Equal(t, 25*time.Second, concatDuration(items))
+	})
+
+	t.Run("Should re-encode with the concat filter", func(t *testing.T) {
+		file, err := concatFilterFile([]ConcatItem{
+			{Path: "a.mp4", Metadata: concatMetadata(1280, 30*time.Second), InPoint: 2 * time.Second},
+			{Path: "b.mp4", Metadata: concatMetadata(960, 30*time.Second)},
+		})
+		require.NoError(t, err)
+		require.NoError(t, file.ValidateFilterGraph())
+		file.SetOutputPath("out.mp4")
+
+		command := file.ToStrCommand()
+		require.Equal(t, []string{"-ss", "2", "-i", "a.mp4", "-i", "b.mp4", "-filter_complex"}, command[:7])
+		require.Contains(t, command[7], "[l0][l1][l2][l3]concat=n=2:v=1:a=1[v][a]")
+		require.Equal(t, []string{"-map", "[v]", "-map", "[a]"}, command[8:12])
+	})
+}
+
+func TestConcatListFile(t *testing.T) {
+	items := []ConcatItem{
+		{Path: "a.mp4", Metadata: concatMetadata(1280, 10*time.Second)},
+		{Path: "b.mp4", Metadata: concatMetadata(1280, 5*time.Second)},
+	}
+	concatTranscoder := func(t *testing.T, fake *goffmpegtest.Fake) (*Transcoder, string) {
+		tmp := t.TempDir()
+		t.Setenv("TMPDIR", tmp)
+		ts := new(Transcoder)
+		ts.SetConfiguration(fake.Configuration())
+		require.NoError(t, ts.InitializeConcat(items, "out.mp4", ConcatModeDemuxer))
+		return ts, tmp
+	}
+	requireEmpty := func(t *testing.T, dir string) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	}
+
+	t.Run("Should write the list when the job starts and remove it once finished", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		ts, tmp := concatTranscoder(t, fake)
+		requireEmpty(t, tmp)
+
+		job, err := ts.Start(context.Background(), false)
+		require.NoError(t, err)
+		require.NoError(t, job.Wait())
+		require.Equal(t, filepath.Dir(ts.MediaFile().InputPath()), tmp)
+		require.Contains(t, fake.LastCall("ffmpeg").Args, ts.MediaFile().InputPath())
+		requireEmpty(t, tmp)
+	})
+
+	t.Run("Should remove the list when the job cannot start", func(t *testing.T) {
+		ts, tmp := concatTranscoder(t, goffmpegtest.New(t))
+		ts.MediaFile().SetHLSEncryption(&media.HLSEncryption{KeyPath: "key.bin", RotationInterval: time.Minute})
+
+		_, err := ts.Start(context.Background(), false)
+		require.Error(t, err)
+		requireEmpty(t, tmp)
+	})
+
+	t.Run("Should not write the list once initialized with another file", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"streams":[{"index":0,"codec_type":"video"}],"format":{"filename":"in.mp4","duration":"10"}}`
+		ts, _ := concatTranscoder(t, fake)
+		require.NoError(t, ts.Initialize("in.mp4", "out.mp4"))
+
+		job, err := ts.Start(context.Background(), false)
+		require.NoError(t, err)
+		require.NoError(t, job.Wait())
+		require.Equal(t, "in.mp4", ts.MediaFile().InputPath())
+		require.Empty(t, ts.setups)
+	})
+}
@@ -0,0 +1,26 @@
+package transcoder
+
+import (
+	"time"
+
+	"github.com/graux/goffmpeg/media"
+)
+
+// rotateKeys writes a new HLS key every rotation interval until the job ends,
+// stopping the job when a key cannot be written or stored
+func (j *Job) rotateKeys(encryption *media.HLSEncryption, outputPath string) {
+	ticker := time.NewTicker(encryption.RotationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := encryption.Rotate(outputPath); err != nil {
+				j.fail(err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/pkg/cmd"
+)
+
+// DefaultStopGracePeriod is the time given to ffmpeg after each stop step
+// before escalating to the next one.
+const DefaultStopGracePeriod = 5 * time.Second
+
+// StopReason describes which step of the stop sequence ended the process.
+type StopReason string
+
+const (
+	StopReasonNone      StopReason = ""
+	StopReasonQuit      StopReason = "quit"
+	StopReasonTerminate StopReason = "terminate"
+	StopReasonKill      StopReason = "kill"
+)
+
+// StopError is returned when the transcoding context ends before ffmpeg does.
+type StopError struct {
+	Reason StopReason
+	Err    error
+}
+
+func (e *StopError) Error() string {
+	return fmt.Sprintf("ffmpeg stopped by %s: %s", e.Reason, e.Err)
+}
+
+// Is reports the process as killed when it did not quit by itself
+func (e *StopError) Is(target error) bool {
+	return target == goffmpeg.ErrKilled && e.Reason != StopReasonQuit
+}
+
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+// ErrStopped is the cause of the StopError returned after Job.Stop.
+var ErrStopped = errors.New("stop requested")
+
+// Result is the final state of a finished Job.
+type Result struct {
+	ExitCode   int
+	StopReason StopReason
+	StartedAt  time.Time
+	Elapsed    time.Duration
+	Progress   Progress
+	Outputs    []OutputResult
+	Err        error
+}
+
+// OutputResult is the final state of one output of the job
+type OutputResult struct {
+	Index  int
+	Target string
+	Size   int64 // bytes on disk, zero for pipes and URLs
+	Err    error // set when a local output was not written
+}
+
+// Job is a running ffmpeg process. All of its methods are safe for concurrent use.
+type Job struct {
+	proc        *exec.Cmd
+	stdin       io.Writer
+	stderr      *cmd.TailBuffer
+	outputs     []string
+	gracePeriod time.Duration
+	startedAt   time.Time
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	progress chan Progress
+	done     chan struct{}
+
+	mu           sync.Mutex
+	lastProgress Progress
+	stopped      bool
+	result       *Result
+}
+
+func newJob(ctx context.Context, proc *exec.Cmd, stdin io.Writer, stderr *cmd.TailBuffer, outputs []string, gracePeriod time.Duration) *Job {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Job{
+		proc:        proc,
+		stdin:       stdin,
+		stderr:      stderr,
+		outputs:     outputs,
+		gracePeriod: gracePeriod,
+		startedAt:   time.Now(),
+		ctx:         ctx,
+		cancel:      cancel,
+		progress:    make(chan Progress, 1),
+		done:        make(chan struct{}),
+	}
+}
+
+// PID Get the ffmpeg process id
+func (j *Job) PID() int {
+	return j.proc.Process.Pid
+}
+
+// Progress Returns the progress channel. It only keeps the latest value, so a
+// slow or absent reader never blocks ffmpeg, and it is closed when the job ends.
+func (j *Job) Progress() <-chan Progress {
+	return j.progress
+}
+
+// Done Returns a channel closed when the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait Blocks until the job has finished and returns its error
+func (j *Job) Wait() error {
+	<-j.done
+	return j.Err()
+}
+
+// Err Returns the final error, or nil while the job is running
+func (j *Job) Err() error {
+	if result := j.Result(); result != nil {
+		return result.Err
+	}
+	return nil
+}
+
+// Result Returns the final result, or nil while the job is running
+func (j *Job) Result() *Result {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.result
+}
+
+// Stop Requests the job to end, escalating from "q" to SIGTERM and SIGKILL
+func (j *Job) Stop() error {
+	j.mu.Lock()
+	j.stopped = true
+	j.mu.Unlock()
+	j.cancel(ErrStopped)
+	return nil
+}
+
+// fail stops the job with err as the cause of its StopError
+func (j *Job) fail(err error) {
+	j.cancel(err)
+}
+
+// run waits for the process, runs complete when it succeeded and cleanup in any case
+func (j *Job) run(progress io.Reader, total time.Duration, complete func() error, cleanup func()) {
+	exited := make(chan struct{})
+	stopped := make(chan StopReason, 1)
+	go func() {
+		select {
+		case <-j.ctx.Done():
+			stopped <- j.escalate(exited)
+		case <-exited:
+			stopped <- StopReasonNone
+		}
+	}()
+
+	// Wait must not be called before all reads from the progress pipe have completed
+	if progress != nil {
+		readProgress(progress, total, j.outputs, j.startedAt, j.publish)
+	}
+	close(j.progress)
+
+	err := j.proc.Wait()
+	close(exited)
+	reason := <-stopped
+	j.cancel(nil)
+
+	var completeErr error
+	if err == nil && reason == StopReasonNone {
+		completeErr = complete()
+	}
+	cleanup()
+
+	j.mu.Lock()
+	result := &Result{
+		ExitCode:   j.proc.ProcessState.ExitCode(),
+		StopReason: reason,
+		StartedAt:  j.startedAt,
+		Elapsed:    time.Since(j.startedAt),
+		Progress:   j.lastProgress,
+		Outputs:    j.outputResults(),
+	}
+	switch {
+	case reason != StopReasonNone && j.stopped:
+		result.Err = &StopError{Reason: reason, Err: ErrStopped}
+	case reason != StopReasonNone:
+		result.Err = &StopError{Reason: reason, Err: context.Cause(j.ctx)}
+	case err != nil:
+		result.Err = goffmpeg.NewFFmpegError(j.proc.Args, err, j.stderr.Lines())
+	default:
+		result.Err = completeErr
+	}
+	j.result = result
+	j.mu.Unlock()
+
+	close(j.done)
+}
+
+func (j *Job) outputResults() []OutputResult {
+	results := make([]OutputResult, len(j.outputs))
+	for i, target := range j.outputs {
+		results[i] = OutputResult{Index: i, Target: target, Size: fileSize(target)}
+		if results[i].Size == 0 && isLocalFile(target) {
+			if _, err := os.Stat(target); err != nil {
+				results[i].Err = err
+			}
+		}
+	}
+	return results
+}
+
+// publish replaces any unread progress with p
+func (j *Job) publish(p Progress) {
+	j.mu.Lock()
+	j.lastProgress = p
+	j.mu.Unlock()
+
+	for {
+		select {
+		case j.progress <- p:
+			return
+		default:
+		}
+		select {
+		case <-j.progress:
+		default:
+		}
+	}
+}
+
+// escalate stops the process step by step: "q" on stdin, SIGTERM and finally
+// SIGKILL, waiting the grace period between steps. It returns the step that
+// ended it, or StopReasonNone when the process exited by itself meanwhile.
+func (j *Job) escalate(exited <-chan struct{}) StopReason {
+	if hasExited(exited) {
+		return StopReasonNone
+	}
+	if j.stdin != nil {
+		if _, err := j.stdin.Write([]byte("q\n")); err == nil && waitExit(exited, j.gracePeriod) {
+			return StopReasonQuit
+		}
+		if hasExited(exited) {
+			return StopReasonNone
+		}
+	}
+
+	err := j.proc.Process.Signal(syscall.SIGTERM)
+	if errors.Is(err, os.ErrProcessDone) {
+		return StopReasonNone
+	}
+	if err == nil && waitExit(exited, j.gracePeriod) {
+		return StopReasonTerminate
+	}
+
+	if err := j.proc.Process.Kill(); errors.Is(err, os.ErrProcessDone) {
+		return StopReasonNone
+	}
+	return StopReasonKill
+}
+
+// hasExited reports whether exited is closed, without waiting
+func hasExited(exited <-chan struct{}) bool {
+	select {
+	case <-exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func waitExit(exited <-chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
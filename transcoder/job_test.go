@@ -0,0 +1,49 @@
+package transcoder
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJob(t *testing.T) {
+	t.Run("Should be safe for concurrent Wait, Stop and Progress", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg = goffmpegtest.Script{Progress: []goffmpegtest.ProgressBlock{{"progress": "continue"}}, Hang: true}
+
+		job, err := fakeTranscoder(fake).Start(context.Background(), true)
+		require.NoError(t, err)
+		waitProgress(t, job)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 4)
+		for i := range errs {
+			wg.Add(3)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = job.Wait()
+			}(i)
+			go func() {
+				defer wg.Done()
+				for range job.Progress() {
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				_ = job.Result()
+				_ = job.Stop()
+			}()
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			require.ErrorIs(t, err, ErrStopped)
+			require.Same(t, job.Err(), err)
+		}
+		require.NotNil(t, job.Result())
+		<-job.Done()
+	})
+}
@@ -0,0 +1,32 @@
+package transcoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/graux/goffmpeg/media"
+)
+
+func TestMain(m *testing.M) {
+	goffmpegtest.Main(m)
+}
+
+func fakeTranscoder(fake *goffmpegtest.Fake) *Transcoder {
+	ts := new(Transcoder)
+	ts.SetConfiguration(fake.Configuration())
+	ts.SetMediaFile(&media.File{})
+	ts.MediaFile().SetInputPath("in.mp4")
+	ts.MediaFile().SetOutputPath("out.mp4")
+	ts.SetStopGracePeriod(time.Second)
+	return ts
+}
+
+// waitProgress waits for the first progress value, sent once the fake is ready
+func waitProgress(t *testing.T, job *Job) {
+	select {
+	case <-job.Progress():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no progress received")
+	}
+}
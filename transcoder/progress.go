@@ -1,9 +1,225 @@
 package transcoder
 
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is a snapshot of a running transcoding. The string fields keep the
+// raw ffmpeg values, the typed ones are parsed from them.
 type Progress struct {
 	FramesProcessed string
 	CurrentTime     string
 	CurrentBitrate  string
 	Progress        float64
 	Speed           string
+
+	Frames      int64
+	FPS         float64
+	OutTime     time.Duration
+	BitRate     float64 // bits per second
+	SpeedFactor float64
+	TotalSize   int64 // bytes written so far
+	DupFrames   int64
+	DropFrames  int64
+	Elapsed     time.Duration
+	ETA         time.Duration // zero when the total duration or the pace is unknown
+	Finished    bool
+	Outputs     []OutputProgress
+}
+
+// OutputProgress is the progress of one output of the job
+type OutputProgress struct {
+	Index   int
+	Target  string
+	Size    int64   // bytes on disk, zero for pipes and URLs
+	Quality float64 // encoder quality of the first stream
+}
+
+// progressPipe carries the output of ffmpeg's -progress option. It uses an
+// extra file descriptor, or stdout on platforms without extra descriptors.
+type progressPipe struct {
+	url string
+	r   io.ReadCloser
+	w   *os.File
+}
+
+func newProgressPipe(proc *exec.Cmd, outputPipe bool) (*progressPipe, error) {
+	if runtime.GOOS == "windows" {
+		if outputPipe {
+			return nil, errors.New("stdout is already used by the output pipe")
+		}
+		r, err := proc.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		return &progressPipe{url: "pipe:1", r: r}, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	proc.ExtraFiles = append(proc.ExtraFiles, w)
+	return &progressPipe{url: "pipe:" + strconv.Itoa(2+len(proc.ExtraFiles)), r: r, w: w}, nil
+}
+
+// started closes the parent's copy of the write end once the child owns it
+func (p *progressPipe) started() {
+	if p != nil && p.w != nil {
+		p.w.Close()
+		p.w = nil
+	}
+}
+
+func (p *progressPipe) reader() io.Reader {
+	if p == nil {
+		return nil
+	}
+	return p.r
+}
+
+func (p *progressPipe) Close() error {
+	if p == nil {
+		return nil
+	}
+	p.started()
+	return p.r.Close()
+}
+
+// readProgress parses the key=value blocks written by -progress and calls emit
+// at the end of each block. total is the expected output duration, or zero when unknown.
+func readProgress(r io.Reader, total time.Duration, outputs []string, startedAt time.Time, emit func(Progress)) {
+	scanner := bufio.NewScanner(r)
+	block := make(map[string]string)
+
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if key != "progress" {
+			block[key] = value
+			continue
+		}
+
+		progress := newProgress(block, value == "end", total, time.Since(startedAt))
+		progress.Outputs = newOutputProgress(block, outputs)
+		emit(progress)
+		block = make(map[string]string)
+	}
+}
+
+func newProgress(block map[string]string, finished bool, total time.Duration, elapsed time.Duration) Progress {
+	progress := Progress{
+		FramesProcessed: block["frame"],
+		CurrentTime:     block["out_time"],
+		CurrentBitrate:  block["bitrate"],
+		Speed:           block["speed"],
+		Frames:          parseInt(block["frame"]),
+		FPS:             parseFloat(block["fps"]),
+		OutTime:         time.Duration(parseInt(block["out_time_us"])) * time.Microsecond,
+		BitRate:         parseBitRate(block["bitrate"]),
+		SpeedFactor:     parseFloat(strings.TrimSuffix(block["speed"], "x")),
+		TotalSize:       parseInt(block["total_size"]),
+		DupFrames:       parseInt(block["dup_frames"]),
+		DropFrames:      parseInt(block["drop_frames"]),
+		Elapsed:         elapsed,
+		Finished:        finished,
+	}
+
+	// live stream check
+	if total > 0 {
+		progress.Progress = float64(progress.OutTime) * 100 / float64(total)
+		progress.ETA = estimateRemaining(total, progress.OutTime, progress.SpeedFactor, elapsed)
+	}
+
+	return progress
+}
+
+func newOutputProgress(block map[string]string, outputs []string) []OutputProgress {
+	progress := make([]OutputProgress, len(outputs))
+	for i, target := range outputs {
+		progress[i] = OutputProgress{
+			Index:   i,
+			Target:  target,
+			Size:    fileSize(target),
+			Quality: parseFloat(block["stream_"+strconv.Itoa(i)+"_0_q"]),
+		}
+	}
+	return progress
+}
+
+// isLocalFile reports whether target is a path rather than a pipe or a URL.
+// A single letter before ":" is a windows drive.
+func isLocalFile(target string) bool {
+	i := strings.Index(target, ":")
+	return target != "" && (i < 0 || i == 1)
+}
+
+// fileSize returns the size of a local output, or zero when it is not a file
+func fileSize(target string) int64 {
+	if !isLocalFile(target) {
+		return 0
+	}
+	info, err := os.Stat(target)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+// estimateRemaining uses the reported speed and falls back to the average pace
+func estimateRemaining(total, done time.Duration, speed float64, elapsed time.Duration) time.Duration {
+	if done <= 0 || done >= total {
+		return 0
+	}
+	remaining := total - done
+	if speed > 0 {
+		return time.Duration(float64(remaining) / speed)
+	}
+	return time.Duration(float64(elapsed) * float64(remaining) / float64(done))
+}
+
+func parseInt(value string) int64 {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func parseFloat(value string) float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseBitRate converts values like "512.3kbits/s" to bits per second
+func parseBitRate(value string) float64 {
+	value = strings.TrimSuffix(value, "bits/s")
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1e3
+	case strings.HasSuffix(value, "M"), strings.HasSuffix(value, "m"):
+		multiplier = 1e6
+	case strings.HasSuffix(value, "G"), strings.HasSuffix(value, "g"):
+		multiplier = 1e9
+	}
+	if multiplier != 1 {
+		value = value[:len(value)-1]
+	}
+	return parseFloat(value) * multiplier
 }
@@ -0,0 +1,103 @@
+package transcoder
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/stretchr/testify/require"
+)
+
+const progressBlocks = `frame=50
+fps=25.00
+stream_0_0_q=28.0
+bitrate= 512.3kbits/s
+total_size=131072
+out_time_us=2000000
+out_time_ms=2000000
+out_time=00:00:02.000000
+dup_frames=1
+drop_frames=0
+speed=1.01x
+progress=continue
+frame=100
+out_time_us=4000000
+progress=end
+`
+
+func TestReadProgress(t *testing.T) {
+	t.Run("Should emit one progress per block", func(t *testing.T) {
+		var progress []Progress
+		readProgress(strings.NewReader(progressBlocks), 4*time.Second, []string{"out.mp4", "pipe:1"}, time.Now(), func(p Progress) {
+			progress = append(progress, p)
+		})
+
+		require.Len(t, progress, 2)
+		require.Equal(t, "50", progress[0].FramesProcessed)
+		require.Equal(t, "00:00:02.000000", progress[0].CurrentTime)
+		require.Equal(t, "512.3kbits/s", progress[0].CurrentBitrate)
+		require.Equal(t, "1.01x", progress[0].Speed)
+		require.Equal(t, int64(50), progress[0].Frames)
+		require.Equal(t, 25.0, progress[0].FPS)
+		require.Equal(t, 2*time.Second, progress[0].OutTime)
+		require.InDelta(t, 512300.0, progress[0].BitRate, 0.01)
+		require.Equal(t, 1.01, progress[0].SpeedFactor)
+		require.Equal(t, int64(131072), progress[0].TotalSize)
+		require.Equal(t, int64(1), progress[0].DupFrames)
+		require.InDelta(t, 1980198019, int64(progress[0].ETA), 1)
+		require.Equal(t, 50.0, progress[0].Progress)
+		require.False(t, progress[0].Finished)
+		require.Equal(t, []OutputProgress{{Index: 0, Target: "out.mp4", Quality: 28}, {Index: 1, Target: "pipe:1"}}, progress[0].Outputs)
+		require.Equal(t, 100.0, progress[1].Progress)
+		require.True(t, progress[1].Finished)
+	})
+
+	t.Run("Should not compute a percentage without a known duration", func(t *testing.T) {
+		var progress []Progress
+		readProgress(strings.NewReader(progressBlocks), 0, nil, time.Now(), func(p Progress) {
+			progress = append(progress, p)
+		})
+
+		require.Len(t, progress, 2)
+		require.Zero(t, progress[0].Progress)
+		require.Zero(t, progress[0].ETA)
+	})
+}
+
+func TestParseBitRate(t *testing.T) {
+	require.InDelta(t, 512300.0, parseBitRate("512.3kbits/s"), 0.01)
+	require.Equal(t, 2e6, parseBitRate("2Mbits/s"))
+	require.Equal(t, 800.0, parseBitRate("800bits/s"))
+	require.Zero(t, parseBitRate("N/A"))
+}
+
+func TestJobProgress(t *testing.T) {
+	t.Run("Should report progress and the final result", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg.Progress = []goffmpegtest.ProgressBlock{
+			goffmpegtest.NewProgressBlock(25, time.Second, 1),
+			goffmpegtest.NewProgressBlock(50, 2*time.Second, 1),
+		}
+		ts := fakeTranscoder(fake)
+		ts.SetProgressDuration(2 * time.Second)
+
+		job, err := ts.Start(context.Background(), true)
+		require.NoError(t, err)
+
+		var last Progress
+		for progress := range job.Progress() {
+			last = progress
+		}
+		require.NoError(t, job.Wait())
+		require.True(t, last.Finished)
+		require.Equal(t, int64(50), last.Frames)
+		require.InDelta(t, 100, last.Progress, 0.001)
+		require.Equal(t, 0, job.Result().ExitCode)
+
+		call := fake.LastCall("ffmpeg")
+		require.NotNil(t, call)
+		require.Equal(t, []string{"-i", "in.mp4", "out.mp4"}, call.Args[len(call.Args)-3:])
+	})
+}
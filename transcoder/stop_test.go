@@ -0,0 +1,80 @@
+package transcoder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/graux/goffmpeg/pkg/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopEscalation(t *testing.T) {
+	t.Run("Should quit on Stop", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg = goffmpegtest.Script{Progress: []goffmpegtest.ProgressBlock{{"progress": "continue"}}, Hang: true}
+
+		job, err := fakeTranscoder(fake).Start(context.Background(), true)
+		require.NoError(t, err)
+		waitProgress(t, job)
+		require.NoError(t, job.Stop())
+
+		err = job.Wait()
+		require.ErrorIs(t, err, ErrStopped)
+		require.NotErrorIs(t, err, goffmpeg.ErrKilled)
+		require.Equal(t, StopReasonQuit, job.Result().StopReason)
+	})
+
+	t.Run("Should terminate when the context ends and quit is ignored", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg = goffmpegtest.Script{Progress: []goffmpegtest.ProgressBlock{{"progress": "continue"}}, Hang: true, IgnoreQuit: true}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		job, err := fakeTranscoder(fake).Start(ctx, true)
+		require.NoError(t, err)
+		waitProgress(t, job)
+		cancel()
+
+		err = job.Wait()
+		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorIs(t, err, goffmpeg.ErrKilled)
+		require.Equal(t, StopReasonTerminate, job.Result().StopReason)
+	})
+
+	t.Run("Should kill when quit and SIGTERM are ignored", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg = goffmpegtest.Script{Progress: []goffmpegtest.ProgressBlock{{"progress": "continue"}}, Hang: true, IgnoreQuit: true, IgnoreTerm: true}
+
+		job, err := fakeTranscoder(fake).Start(context.Background(), true)
+		require.NoError(t, err)
+		waitProgress(t, job)
+		require.NoError(t, job.Stop())
+
+		require.ErrorIs(t, job.Wait(), goffmpeg.ErrKilled)
+		require.Equal(t, StopReasonKill, job.Result().StopReason)
+	})
+
+	t.Run("Should not escalate when ffmpeg exits as the context ends", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		proc := fake.Executor().Command(context.Background(), "ffmpeg", "-i", "in.mp4", "out.mp4")
+		stdin, err := proc.StdinPipe()
+		require.NoError(t, err)
+		require.NoError(t, proc.Start())
+		require.NoError(t, proc.Wait())
+
+		// The process is gone but run has not closed exited yet
+		job := newJob(context.Background(), proc, stdin, cmd.NewTailBuffer(10), nil, time.Second)
+		require.Equal(t, StopReasonNone, job.escalate(make(chan struct{})))
+	})
+}
+
+func TestStopError(t *testing.T) {
+	t.Run("Should only match ErrKilled when ffmpeg did not quit by itself", func(t *testing.T) {
+		require.NotErrorIs(t, &StopError{Reason: StopReasonQuit, Err: ErrStopped}, goffmpeg.ErrKilled)
+		require.ErrorIs(t, &StopError{Reason: StopReasonTerminate, Err: context.DeadlineExceeded}, goffmpeg.ErrKilled)
+		require.ErrorIs(t, &StopError{Reason: StopReasonKill, Err: context.DeadlineExceeded}, context.DeadlineExceeded)
+		require.EqualError(t, &StopError{Reason: StopReasonKill, Err: ErrStopped}, "ffmpeg stopped by kill: stop requested")
+	})
+}
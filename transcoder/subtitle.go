@@ -0,0 +1,34 @@
+package transcoder
+
+import (
+	"fmt"
+
+	"github.com/graux/goffmpeg/media"
+)
+
+// InitializeSubtitleExtraction Init the extraction of the nth subtitle stream of
+// inputPath to outputPath, in the format given by the output extension
+func (t *Transcoder) InitializeSubtitleExtraction(inputPath string, stream int, outputPath string) error {
+	format, err := media.SubtitleFormatFromPath(outputPath)
+	if err != nil {
+		return fmt.Errorf("error on transcoder.InitializeSubtitleExtraction: %w", err)
+	}
+
+	if err := t.Initialize(inputPath, outputPath); err != nil {
+		return err
+	}
+
+	if streams := t.mediafile.Metadata().SubtitleStreams(); stream < 0 || stream >= len(streams) {
+		return fmt.Errorf("error on transcoder.InitializeSubtitleExtraction: %s has %d subtitle streams, %d requested", inputPath, len(streams), stream)
+	}
+
+	t.mediafile.AddMap(media.MapTypeIndex(0, media.StreamTypeSubtitle, stream))
+	t.mediafile.SetSubtitleCodec(string(format))
+	return nil
+}
+
+// InitializeSubtitleConversion Init the conversion of a subtitle file to the
+// format given by the output extension
+func (t *Transcoder) InitializeSubtitleConversion(inputPath string, outputPath string) error {
+	return t.InitializeSubtitleExtraction(inputPath, 0, outputPath)
+}
@@ -0,0 +1,179 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graux/goffmpeg/media"
+)
+
+// SpriteSheet is a grid of thumbnails tiled into a single image
+type SpriteSheet struct {
+	Columns    int
+	Rows       int
+	TileWidth  int
+	TileHeight int    // derived from the source aspect ratio when zero
+	VTTPath    string // WebVTT thumbnail track written after the sprite, not written when empty
+	ImageURL   string // sprite URL used in the track, the base name of the sprite when empty
+}
+
+// NewThumbnails returns a Transcoder writing one frame of the source per timestamp
+// in a single ffmpeg run. outputPattern receives the thumbnail index, e.g. "thumb-%03d.jpg".
+func NewThumbnails(source *Transcoder, outputPattern string, timestamps ...time.Duration) (*Transcoder, error) {
+	inputPath, metadata, err := thumbnailSource(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(timestamps) == 0 {
+		return nil, errors.New("error on transcoder.NewThumbnails: timestamps missing")
+	}
+
+	file := new(media.File)
+	file.SetMetadata(metadata)
+	for i, timestamp := range timestamps {
+		if timestamp < 0 || metadata.Format.Duration > 0 && timestamp >= metadata.Format.Duration {
+			return nil, fmt.Errorf("error on transcoder.NewThumbnails: timestamp %s out of the source duration %s", timestamp, metadata.Format.Duration)
+		}
+		input := file.AddInput(&media.Input{Path: inputPath, SeekTime: formatSeconds(timestamp), Metadata: metadata})
+
+		output := file
+		if i > 0 {
+			output = new(media.File)
+			file.AddOutput(output)
+		}
+		output.AddMap(media.MapTypeIndex(input, media.StreamTypeVideo, 0))
+		output.SetVframes(1)
+		output.SetOutputPath(fmt.Sprintf(outputPattern, i))
+	}
+
+	return thumbnailTranscoder(source, file), nil
+}
+
+// NewEvenThumbnails returns a Transcoder writing count frames evenly spaced over
+// the source duration, each one in the middle of its interval
+func NewEvenThumbnails(source *Transcoder, outputPattern string, count int) (*Transcoder, error) {
+	_, metadata, err := thumbnailSource(source)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, errors.New("error on transcoder.NewEvenThumbnails: count must be positive")
+	}
+
+	timestamps := evenTimestamps(metadata.Format.Duration, count)
+	if timestamps == nil {
+		return nil, errors.New("error on transcoder.NewEvenThumbnails: source duration unknown")
+	}
+	return NewThumbnails(source, outputPattern, timestamps...)
+}
+
+// NewSpriteSheet returns a Transcoder tiling thumbnails of the whole source into
+// outputPath. When VTTPath is set, the matching WebVTT thumbnail track is written
+// once the sprite has been.
+func NewSpriteSheet(source *Transcoder, outputPath string, sprite SpriteSheet) (*Transcoder, error) {
+	inputPath, metadata, err := thumbnailSource(source)
+	if err != nil {
+		return nil, err
+	}
+	if sprite.Columns <= 0 || sprite.Rows <= 0 || sprite.TileWidth <= 0 {
+		return nil, errors.New("error on transcoder.NewSpriteSheet: grid and tile width must be positive")
+	}
+	duration := metadata.Format.Duration
+	if duration <= 0 {
+		return nil, errors.New("error on transcoder.NewSpriteSheet: source duration unknown")
+	}
+	if sprite.TileHeight <= 0 {
+		video := metadata.FirstVideoStream()
+		if video == nil || video.Width == 0 {
+			return nil, errors.New("error on transcoder.NewSpriteSheet: tile height missing and source has no video size")
+		}
+		// Keep the height even for chroma subsampled formats
+		sprite.TileHeight = (sprite.TileWidth*video.Height/video.Width + 1) &^ 1
+	}
+
+	tiles := sprite.Columns * sprite.Rows
+	file := new(media.File)
+	file.SetMetadata(metadata)
+	file.SetInputPath(inputPath)
+	file.SetVideoFilter(fmt.Sprintf("fps=%s,scale=%d:%d,tile=%dx%d",
+		strconv.FormatFloat(float64(tiles)/duration.Seconds(), 'f', 6, 64),
+		sprite.TileWidth, sprite.TileHeight, sprite.Columns, sprite.Rows))
+	file.SetVframes(1)
+	file.SetSkipAudio(true)
+	file.SetOutputPath(outputPath)
+
+	tr := thumbnailTranscoder(source, file)
+	if sprite.VTTPath != "" {
+		if sprite.ImageURL == "" {
+			sprite.ImageURL = filepath.Base(outputPath)
+		}
+		tr.addCompletion(func() error {
+			return os.WriteFile(sprite.VTTPath, []byte(spriteVTT(sprite, duration)), 0o644)
+		})
+	}
+	return tr, nil
+}
+
+// spriteVTT returns a WebVTT track pointing every interval to its tile
+func spriteVTT(sprite SpriteSheet, duration time.Duration) string {
+	tiles := sprite.Columns * sprite.Rows
+	interval := duration / time.Duration(tiles)
+
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	for i := 0; i < tiles; i++ {
+		end := time.Duration(i+1) * interval
+		if i == tiles-1 {
+			end = duration
+		}
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(time.Duration(i)*interval), formatVTTTimestamp(end), sprite.ImageURL,
+			i%sprite.Columns*sprite.TileWidth, i/sprite.Columns*sprite.TileHeight, sprite.TileWidth, sprite.TileHeight)
+	}
+	return vtt.String()
+}
+
+func formatVTTTimestamp(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second), int(d%time.Second/time.Millisecond))
+}
+
+func evenTimestamps(duration time.Duration, count int) []time.Duration {
+	if duration <= 0 {
+		return nil
+	}
+	timestamps := make([]time.Duration, count)
+	for i := range timestamps {
+		timestamps[i] = duration * time.Duration(2*i+1) / time.Duration(2*count)
+	}
+	return timestamps
+}
+
+func thumbnailSource(source *Transcoder) (string, *media.Metadata, error) {
+	if source == nil || source.MediaFile() == nil {
+		return "", nil, errors.New("error on thumbnails: source transcoder not initialized")
+	}
+	inputPath := source.MediaFile().InputPath()
+	if inputPath == "" {
+		return "", nil, errors.New("error on thumbnails: source has no input path")
+	}
+	metadata := source.MediaFile().Metadata()
+	if metadata == nil {
+		metadata = new(media.Metadata)
+	}
+	return inputPath, metadata, nil
+}
+
+func thumbnailTranscoder(source *Transcoder, file *media.File) *Transcoder {
+	tr := new(Transcoder)
+	tr.SetConfiguration(source.configuration)
+	tr.SetWhiteListProtocols(source.whiteListProtocols)
+	tr.SetMediaFile(file)
+	return tr
+}
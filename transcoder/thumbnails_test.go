@@ -0,0 +1,80 @@
+package transcoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graux/goffmpeg/goffmpegtest"
+	"github.com/graux/goffmpeg/media"
+	"github.com/stretchr/testify/require"
+)
+
+func thumbnailSourceTranscoder() *Transcoder {
+	source := &Transcoder{}
+	source.SetMediaFile(&media.File{})
+	source.MediaFile().SetInputPath("in.mp4")
+	source.MediaFile().SetMetadata(&media.Metadata{
+		Format:  media.Format{Duration: 40 * time.Second},
+		Streams: []media.Stream{{CodecType: media.CodecTypeVideo, Width: 1280, Height: 720}},
+	})
+	return source
+}
+
+func TestThumbnails(t *testing.T) {
+	t.Run("Should seek every thumbnail in a single run", func(t *testing.T) {
+		tr, err := NewEvenThumbnails(thumbnailSourceTranscoder(), "thumb-%02d.jpg", 2)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"-y", "-ss", "10", "-i", "in.mp4", "-ss", "30", "-i", "in.mp4",
+			"-map", "0:v:0", "-vframes", "1", "thumb-00.jpg",
+			"-map", "1:v:0", "-vframes", "1", "thumb-01.jpg",
+		}, tr.GetCommand())
+	})
+
+	t.Run("Should reject timestamps out of the source", func(t *testing.T) {
+		_, err := NewThumbnails(thumbnailSourceTranscoder(), "thumb-%02d.jpg", time.Minute)
+		require.Error(t, err)
+	})
+
+	t.Run("Should tile a sprite sheet and write its WebVTT track", func(t *testing.T) {
+		vttPath := filepath.Join(t.TempDir(), "sprite.vtt")
+		tr, err := NewSpriteSheet(thumbnailSourceTranscoder(), "sprite.jpg", SpriteSheet{Columns: 2, Rows: 2, TileWidth: 160, VTTPath: vttPath})
+		require.NoError(t, err)
+		require.Equal(t, "fps=0.100000,scale=160:90,tile=2x2", tr.MediaFile().VideoFilter())
+		require.NoFileExists(t, vttPath)
+
+		tr.SetConfiguration(goffmpegtest.New(t).Configuration())
+		require.NoError(t, <-tr.Run(false))
+		vtt, err := os.ReadFile(vttPath)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+sprite.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+sprite.jpg#xywh=160,0,160,90
+
+00:00:20.000 --> 00:00:30.000
+sprite.jpg#xywh=0,90,160,90
+
+00:00:30.000 --> 00:00:40.000
+sprite.jpg#xywh=160,90,160,90
+`, string(vtt))
+	})
+
+	t.Run("Should not write the WebVTT track of a failed sprite", func(t *testing.T) {
+		vttPath := filepath.Join(t.TempDir(), "sprite.vtt")
+		tr, err := NewSpriteSheet(thumbnailSourceTranscoder(), "sprite.jpg", SpriteSheet{Columns: 2, Rows: 2, TileWidth: 160, VTTPath: vttPath})
+		require.NoError(t, err)
+
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg.ExitCode = 1
+		tr.SetConfiguration(fake.Configuration())
+		require.Error(t, <-tr.Run(false))
+		require.NoFileExists(t, vttPath)
+	})
+}
@@ -1,29 +1,30 @@
 package transcoder
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"os/exec"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/graux/goffmpeg"
 	"github.com/graux/goffmpeg/media"
-	"github.com/graux/goffmpeg/pkg/duration"
+	"github.com/graux/goffmpeg/pkg/cmd"
 )
 
 // Transcoder Main struct
 type Transcoder struct {
-	stdErrPipe         io.ReadCloser
-	stdStdinPipe       io.WriteCloser
-	process            *exec.Cmd
+	job                *Job
 	mediafile          *media.File
 	configuration      goffmpeg.Configuration
 	whiteListProtocols []string
+	stopGracePeriod    time.Duration
+	logWriter          io.Writer
+	progressDuration   time.Duration
+	setups             []func() error
+	completions        []func() error
+	cleanups           []func()
 }
 
 func NewTranscoder(sourceFile, targetFile string) (*Transcoder, error) {
@@ -34,21 +35,6 @@ func NewTranscoder(sourceFile, targetFile string) (*Transcoder, error) {
 	return tr, nil
 }
 
-// SetProcessStderrPipe Set the STDERR pipe
-func (t *Transcoder) SetProcessStderrPipe(v io.ReadCloser) {
-	t.stdErrPipe = v
-}
-
-// SetProcessStdinPipe Set the STDIN pipe
-func (t *Transcoder) SetProcessStdinPipe(v io.WriteCloser) {
-	t.stdStdinPipe = v
-}
-
-// SetProcess Set the transcoding process
-func (t *Transcoder) SetProcess(cmd *exec.Cmd) {
-	t.process = cmd
-}
-
 // SetMediaFile Set the media file
 func (t *Transcoder) SetMediaFile(v *media.File) {
 	t.mediafile = v
@@ -63,9 +49,45 @@ func (t *Transcoder) SetWhiteListProtocols(availableProtocols []string) {
 	t.whiteListProtocols = availableProtocols
 }
 
-// Process Get transcoding process
-func (t Transcoder) Process() *exec.Cmd {
-	return t.process
+// SetStopGracePeriod Set the time to wait after sending "q" and after SIGTERM
+// before moving to the next stop step
+func (t *Transcoder) SetStopGracePeriod(v time.Duration) {
+	t.stopGracePeriod = v
+}
+
+// SetLogWriter Set the writer receiving the ffmpeg log written to stderr
+func (t *Transcoder) SetLogWriter(w io.Writer) {
+	t.logWriter = w
+}
+
+// SetProgressDuration Set the expected output duration progress is computed
+// against, instead of the duration of the first input
+func (t *Transcoder) SetProgressDuration(v time.Duration) {
+	t.progressDuration = v
+}
+
+// ProgressDuration Get the expected output duration, zero when unknown
+func (t Transcoder) ProgressDuration() time.Duration {
+	if t.progressDuration > 0 {
+		return t.progressDuration
+	}
+	if metadata := t.mediafile.InputMetadata(0); metadata != nil {
+		return metadata.Format.Duration
+	}
+	return 0
+}
+
+// StopGracePeriod Get the grace period used when stopping the process
+func (t Transcoder) StopGracePeriod() time.Duration {
+	if t.stopGracePeriod <= 0 {
+		return DefaultStopGracePeriod
+	}
+	return t.stopGracePeriod
+}
+
+// Job Get the last started transcoding job
+func (t Transcoder) Job() *Job {
+	return t.job
 }
 
 // MediaFile Get the ttranscoding media file.
@@ -86,6 +108,9 @@ func (t Transcoder) FFprobeExec() string {
 // GetCommand Build and get command
 func (t Transcoder) GetCommand() []string {
 	media := t.mediafile
+	if version := t.configuration.FFmpegVersion(); !version.IsZero() {
+		media.SetFFmpegVersion(version)
+	}
 	rcommand := append([]string{"-y"}, media.ToStrCommand()...)
 
 	if t.whiteListProtocols != nil {
@@ -110,6 +135,7 @@ func (t *Transcoder) InitializeEmptyTranscoder() error {
 	MediaFile.SetMetadata(new(media.Metadata))
 
 	// Set transcoder configuration
+	t.resetHooks()
 	t.SetMediaFile(MediaFile)
 	t.SetConfiguration(cfg)
 	return nil
@@ -160,6 +186,34 @@ func (t *Transcoder) CreateOutputPipe(containerFormat string) (*io.PipeReader, e
 	return outputPipeReader, nil
 }
 
+// AddInput probes and appends an input after the main one, returning its ffmpeg
+// input index. Inputs with Metadata already set are not probed again.
+func (t *Transcoder) AddInput(input *media.Input) (int, error) {
+	if input.Path == "" {
+		return 0, errors.New("error on transcoder.AddInput: input path missing")
+	}
+	if t.mediafile == nil {
+		if err := t.InitializeEmptyTranscoder(); err != nil {
+			return 0, err
+		}
+	}
+
+	if input.Metadata == nil {
+		metadata, err := media.NewMetadata(t.configuration, input.Path, t.whiteListProtocols...)
+		if err != nil {
+			return 0, err
+		}
+		input.Metadata = metadata
+	}
+
+	return t.mediafile.AddInput(input), nil
+}
+
+// InputMetadata Get the metadata of the input at the ffmpeg input index
+func (t Transcoder) InputMetadata(index int) *media.Metadata {
+	return t.mediafile.InputMetadata(index)
+}
+
 // Initialize Init the transcoding process
 func (t *Transcoder) Initialize(inputPath string, outputPath string) error {
 	var err error
@@ -187,48 +241,95 @@ func (t *Transcoder) Initialize(inputPath string, outputPath string) error {
 	MediaFile.SetOutputPath(outputPath)
 
 	// Set transcoder configuration
+	t.resetHooks()
 	t.SetMediaFile(MediaFile)
 	t.SetConfiguration(cfg)
 
 	return nil
 }
 
-// Run Starts the transcoding process
-func (t *Transcoder) Run(progress bool) <-chan error {
-	done := make(chan error)
-	command := t.GetCommand()
-
-	if !progress {
-		command = append([]string{"-nostats", "-loglevel", "0"}, command...)
+// Start Starts the transcoding process bound to ctx and returns its Job. When
+// ctx is done the process is asked to quit, then terminated and finally killed.
+func (t *Transcoder) Start(ctx context.Context, progress bool) (job *Job, err error) {
+	if err := t.mediafile.ValidateFilterGraph(); err != nil {
+		return nil, err
+	}
+	if err := t.mediafile.ValidateSubtitleTracks(); err != nil {
+		return nil, err
+	}
+	if capabilities := t.configuration.Capabilities(); capabilities != nil {
+		if err := t.mediafile.CheckCapabilities(capabilities); err != nil {
+			return nil, err
+		}
 	}
 
-	proc := exec.Command(t.configuration.FFmpegBinPath(), command...)
-	if progress {
-		errStream, err := proc.StderrPipe()
+	// Cleanups run when the job has finished, or now when it could not start
+	for _, setup := range t.setups {
+		if err = setup(); err != nil {
+			break
+		}
+	}
+	cleanups := t.takeCleanups()
+	defer func() {
 		if err != nil {
-			fmt.Println("Progress not available: " + err.Error())
-		} else {
-			t.stdErrPipe = errStream
+			cleanups()
 		}
+	}()
+	if err != nil {
+		return nil, err
 	}
 
-	// Set the stdinPipe in case we need to stop the transcoding
-	stdin, err := proc.StdinPipe()
-	if nil != err {
-		fmt.Println("Stdin not available: " + err.Error())
+	if ladder := t.mediafile.HLSLadder(); ladder != nil {
+		if err := ladder.WriteMasterPlaylist(t.mediafile.Metadata(), t.mediafile.OutputPath()); err != nil {
+			return nil, err
+		}
 	}
+	// Every encrypted output needs its first key before ffmpeg reads the keyinfo
+	files := append([]*media.File{t.mediafile}, t.mediafile.Outputs()...)
+	for _, file := range files {
+		if encryption := file.HLSEncryption(); encryption != nil {
+			if err := encryption.Validate(); err != nil {
+				return nil, err
+			}
+			if _, err := encryption.Rotate(file.OutputPath()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	command := t.GetCommand()
 
-	t.stdStdinPipe = stdin
+	// The job stops the process itself when ctx is done, escalating from "q"
+	proc := t.configuration.Executor().Command(context.Background(), t.configuration.FFmpegBinPath())
+	stderr := cmd.NewTailBuffer(goffmpeg.DefaultStderrLines)
+	proc.Stderr = stderr
+	if t.logWriter != nil {
+		proc.Stderr = io.MultiWriter(stderr, t.logWriter)
+	}
 
-	// If the user has requested progress, we send it to them on a Buffer
-	var outb, errb bytes.Buffer
+	var progressPipe *progressPipe
 	if progress {
-		proc.Stdout = &outb
+		var err error
+		progressPipe, err = newProgressPipe(proc, t.mediafile.OutputPipe())
+		if err != nil {
+			return nil, fmt.Errorf("progress not available: %w", err)
+		}
+		command = append([]string{"-nostats", "-progress", progressPipe.url}, command...)
+	} else {
+		command = append([]string{"-nostats", "-loglevel", "error"}, command...)
 	}
+	proc.Args = append(proc.Args, command...)
 
-	// If an input pipe has been set, we set it as stdin for the transcoding
+	// Set the stdinPipe in case we need to stop the transcoding
+	var stdin io.WriteCloser
 	if t.mediafile.InputPipe() {
 		proc.Stdin = t.mediafile.InputPipeReader()
+	} else {
+		stdinPipe, err := proc.StdinPipe()
+		if err != nil {
+			progressPipe.Close()
+			return nil, fmt.Errorf("stdin not available: %w", err)
+		}
+		stdin = stdinPipe
 	}
 
 	// If an output pipe has been set, we set it as stdout for the transcoding
@@ -237,139 +338,115 @@ func (t *Transcoder) Run(progress bool) <-chan error {
 	}
 
 	err = proc.Start()
+	progressPipe.started()
+	if err != nil {
+		progressPipe.Close()
+		t.closePipes()
+		return nil, goffmpeg.NewFFmpegError(proc.Args, err, nil)
+	}
 
-	t.SetProcess(proc)
-
-	go func(err error) {
-		if err != nil {
-			done <- fmt.Errorf("failed start ffmpeg (%s) with %s, message %s %s", command, err, outb.String(), errb.String())
-			close(done)
-			return
+	job = newJob(ctx, proc, stdin, stderr, t.mediafile.OutputTargets(), t.StopGracePeriod())
+	t.job = job
+
+	go job.run(progressPipe.reader(), t.ProgressDuration(), t.complete, func() {
+		progressPipe.Close()
+		t.closePipes()
+		cleanups()
+	})
+	for _, file := range files {
+		if encryption := file.HLSEncryption(); encryption != nil && encryption.RotationInterval > 0 {
+			go job.rotateKeys(encryption, file.OutputPath())
 		}
+	}
 
-		err = proc.Wait()
-		if err != nil {
-			err = fmt.Errorf("failed finish ffmpeg (%s) with %s message %s %s", command, err, outb.String(), errb.String())
-		}
+	return job, nil
+}
+
+// Run Starts the transcoding process
+func (t *Transcoder) Run(progress bool) <-chan error {
+	return t.RunContext(context.Background(), progress)
+}
 
-		go t.closePipes()
+// RunContext Starts the transcoding process bound to ctx and returns a buffered
+// channel receiving its final error.
+func (t *Transcoder) RunContext(ctx context.Context, progress bool) <-chan error {
+	done := make(chan error, 1)
 
+	job, err := t.Start(ctx, progress)
+	if err != nil {
 		done <- err
 		close(done)
-	}(err)
+		return done
+	}
+
+	go func() {
+		done <- job.Wait()
+		close(done)
+	}()
 
 	return done
 }
 
 // Stop Ends the transcoding process
 func (t *Transcoder) Stop() error {
-	if t.process != nil {
-		stdin := t.stdStdinPipe
-		if stdin != nil {
-			if _, err := stdin.Write([]byte("q\n")); err != nil {
-				return err
-			}
-		}
+	if t.job != nil {
+		return t.job.Stop()
 	}
 	return nil
 }
 
 // Output Returns the transcoding progress channel
-func (t Transcoder) Output() <-chan Progress {
-	out := make(chan Progress)
+func (t *Transcoder) Output() <-chan Progress {
+	if t.job != nil {
+		return t.job.Progress()
+	}
 
-	go func() {
-		defer close(out)
-		if t.stdErrPipe == nil {
-			out <- Progress{}
-			return
-		}
+	out := make(chan Progress, 1)
+	out <- Progress{}
+	close(out)
+	return out
+}
 
-		defer t.stdErrPipe.Close()
+// resetHooks drops the setups and completions of the previous media file
+func (t *Transcoder) resetHooks() {
+	t.setups = nil
+	t.completions = nil
+}
 
-		scanner := bufio.NewScanner(t.stdErrPipe)
+// addSetup registers f to run whenever a job starts, before its command is built
+func (t *Transcoder) addSetup(f func() error) {
+	t.setups = append(t.setups, f)
+}
 
-		split := func(data []byte, atEOF bool) (advance int, token []byte, spliterror error) {
-			if atEOF && len(data) == 0 {
-				return 0, nil, nil
-			}
-			// windows \r\n
-			// so  first \r and then \n can remove unexpected line break
-			if i := bytes.IndexByte(data, '\r'); i >= 0 {
-				// We have a cr terminated line
-				return i + 1, data[0:i], nil
-			}
-			if i := bytes.IndexByte(data, '\n'); i >= 0 {
-				// We have a full newline-terminated line.
-				return i + 1, data[0:i], nil
-			}
-			if atEOF {
-				return len(data), data, nil
-			}
+// addCompletion registers f to run whenever a job succeeds. Its error becomes
+// the error of the job.
+func (t *Transcoder) addCompletion(f func() error) {
+	t.completions = append(t.completions, f)
+}
 
-			return 0, nil, nil
+func (t *Transcoder) complete() error {
+	for _, f := range t.completions {
+		if err := f(); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		scanner.Split(split)
-		buf := make([]byte, 2)
-		scanner.Buffer(buf, bufio.MaxScanTokenSize)
-
-		for scanner.Scan() {
-			Progress := new(Progress)
-			line := scanner.Text()
-			if strings.Contains(line, "frame=") && strings.Contains(line, "time=") && strings.Contains(line, "bitrate=") {
-				re := regexp.MustCompile(`=\s+`)
-				st := re.ReplaceAllString(line, `=`)
-
-				f := strings.Fields(st)
-				var framesProcessed string
-				var currentTime string
-				var currentBitrate string
-				var currentSpeed string
-
-				for j := 0; j < len(f); j++ {
-					field := f[j]
-					fieldSplit := strings.Split(field, "=")
-
-					if len(fieldSplit) > 1 {
-						fieldname := strings.Split(field, "=")[0]
-						fieldvalue := strings.Split(field, "=")[1]
-
-						if fieldname == "frame" {
-							framesProcessed = fieldvalue
-						}
-
-						if fieldname == "time" {
-							currentTime = fieldvalue
-						}
-
-						if fieldname == "bitrate" {
-							currentBitrate = fieldvalue
-						}
-						if fieldname == "speed" {
-							currentSpeed = fieldvalue
-						}
-					}
-				}
-
-				timesec := duration.DurToSec(currentTime)
-				dur := t.MediaFile().Metadata().Format.Duration
-				// live stream check
-				if dur > 0 {
-					// Progress calculation
-					progress := (timesec * 100) / dur.Seconds()
-					Progress.Progress = progress
-				}
-				Progress.CurrentBitrate = currentBitrate
-				Progress.FramesProcessed = framesProcessed
-				Progress.CurrentTime = currentTime
-				Progress.Speed = currentSpeed
-				out <- *Progress
-			}
-		}
-	}()
+// addCleanup registers f to run once the next job has finished
+func (t *Transcoder) addCleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
 
-	return out
+// takeCleanups returns the registered cleanups, leaving none for the next job
+func (t *Transcoder) takeCleanups() func() {
+	cleanups := t.cleanups
+	t.cleanups = nil
+	return func() {
+		for _, f := range cleanups {
+			f()
+		}
+	}
 }
 
 func (t *Transcoder) closePipes() {
@@ -1,9 +1,16 @@
 package transcoder
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/graux/goffmpeg"
+	"github.com/graux/goffmpeg/goffmpegtest"
 	"github.com/graux/goffmpeg/media"
+	"github.com/graux/goffmpeg/pkg/filtergraph"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,6 +20,7 @@ func TestTranscoder(t *testing.T) {
 			ts := Transcoder{}
 
 			ts.SetMediaFile(&media.File{})
+			ts.MediaFile().SetOutputPath("out.mp4")
 			require.NotEqual(t, ts.GetCommand()[0:2], []string{"-protocol_whitelist", "file,http,https,tcp,tls"})
 			require.NotContains(t, ts.GetCommand(), "protocol_whitelist")
 		})
@@ -27,3 +35,115 @@ func TestTranscoder(t *testing.T) {
 		})
 	})
 }
+
+func TestTranscoderInputs(t *testing.T) {
+	t.Run("Should add inputs after the main one with their own options", func(t *testing.T) {
+		ts := Transcoder{}
+		ts.SetMediaFile(&media.File{})
+		ts.MediaFile().SetInputPath("video.mp4")
+		ts.MediaFile().SetOutputPath("out.mp4")
+
+		audio := &media.Metadata{Format: media.Format{Filename: "audio.m4a"}}
+		index, err := ts.AddInput(&media.Input{Path: "audio.m4a", SeekTime: "5", InitialOffset: "0.5", Metadata: audio})
+		require.NoError(t, err)
+		require.Equal(t, 1, index)
+		require.Same(t, audio, ts.InputMetadata(1))
+
+		require.Equal(t, []string{"-y", "-i", "video.mp4", "-itsoffset", "0.5", "-ss", "5", "-i", "audio.m4a", "out.mp4"}, ts.GetCommand())
+	})
+}
+
+func TestTranscoderProbe(t *testing.T) {
+	t.Run("Should probe added inputs with ffprobe", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"streams":[{"index":0,"codec_type":"audio"}],"format":{"filename":"audio.m4a","duration":"12.5"}}`
+		ts := fakeTranscoder(fake)
+
+		index, err := ts.AddInput(&media.Input{Path: "audio.m4a"})
+		require.NoError(t, err)
+		require.Equal(t, 1, index)
+		require.Equal(t, 12500*time.Millisecond, ts.InputMetadata(1).Format.Duration)
+		require.Contains(t, fake.LastCall("ffprobe").Args, "audio.m4a")
+	})
+
+	t.Run("Should keep probed inputs and output state when applying presets", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFprobe.Stdout = `{"streams":[{"index":0,"codec_type":"audio"}],"format":{"filename":"audio.m4a","duration":"12.5"}}`
+		ts := fakeTranscoder(fake)
+		_, err := ts.AddInput(&media.Input{Path: "audio.m4a"})
+		require.NoError(t, err)
+
+		graph := filtergraph.New()
+		graph.Output(graph.Chain([]*filtergraph.Pad{graph.Input("0:v")}, filtergraph.F("scale", "640", "-2")).Out())
+		preview := &media.File{}
+		preview.SetFilterGraph(graph)
+		preview.SetOutputPath("preview.mp4")
+		ts.MediaFile().AddOutput(preview)
+		onKey := func(*media.HLSKey) error { return nil }
+		ts.MediaFile().SetHLSEncryption(&media.HLSEncryption{OnKey: onKey})
+
+		registry := media.NewPresetRegistry()
+		require.NoError(t, registry.RegisterOptions("web", media.FileOptions{VideoCodec: "libx264"}))
+		require.NoError(t, registry.Apply(ts.MediaFile(), "web"))
+
+		require.Equal(t, "libx264", ts.MediaFile().VideoCodec())
+		require.Equal(t, 12500*time.Millisecond, ts.InputMetadata(1).Format.Duration)
+		require.Same(t, preview, ts.MediaFile().Outputs()[0])
+		require.Same(t, graph, preview.FilterGraph())
+		require.NotNil(t, ts.MediaFile().HLSEncryption().OnKey)
+	})
+}
+
+func TestTranscoderOutputs(t *testing.T) {
+	t.Run("Should write every output with its own options", func(t *testing.T) {
+		ts := Transcoder{}
+		ts.SetMediaFile(&media.File{})
+		ts.MediaFile().SetInputPath("in.mp4")
+		ts.MediaFile().SetResolution("1920x1080")
+		ts.MediaFile().SetOutputPath("1080p.mp4")
+
+		audio := &media.File{}
+		audio.SetSkipVideo(true)
+		audio.SetAudioCodec("aac")
+		audio.SetOutputPath("audio.m4a")
+		require.Equal(t, 1, ts.MediaFile().AddOutput(audio))
+
+		command := ts.GetCommand()
+		require.Equal(t, []string{"-vn", "-c:a", "aac", "audio.m4a"}, command[len(command)-4:])
+		require.Equal(t, []string{"1080p.mp4", "audio.m4a"}, ts.MediaFile().OutputTargets())
+	})
+
+	t.Run("Should write the first key of every encrypted output", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		ts := fakeTranscoder(fake)
+		dir := t.TempDir()
+		hls := &media.File{}
+		hls.SetOutputFormat("hls")
+		hls.SetOutputPath(filepath.Join(dir, "index.m3u8"))
+		hls.SetHLSEncryption(&media.HLSEncryption{})
+		ts.MediaFile().AddOutput(hls)
+
+		job, err := ts.Start(context.Background(), false)
+		require.NoError(t, err)
+		require.NoError(t, job.Wait())
+		require.Contains(t, fake.LastCall("ffmpeg").Args, filepath.Join(dir, "index.keyinfo"))
+		require.FileExists(t, filepath.Join(dir, "index.keyinfo"))
+		require.FileExists(t, filepath.Join(dir, "index-0.key"))
+	})
+}
+
+func TestTranscoderErrors(t *testing.T) {
+	t.Run("Should classify the ffmpeg error", func(t *testing.T) {
+		fake := goffmpegtest.New(t)
+		fake.FFmpeg = goffmpegtest.Script{Stderr: []string{"in.mp4: No such file or directory"}, ExitCode: 1}
+
+		job, err := fakeTranscoder(fake).Start(context.Background(), false)
+		require.NoError(t, err)
+
+		err = job.Wait()
+		require.ErrorIs(t, err, goffmpeg.ErrInputNotFound)
+		var ffmpegErr *goffmpeg.FFmpegError
+		require.True(t, errors.As(err, &ffmpegErr))
+		require.Equal(t, 1, ffmpegErr.ExitCode)
+	})
+}
@@ -0,0 +1,160 @@
+package goffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedVersion is returned by Configure when ffmpeg or ffprobe are
+// older than the minimum version
+var ErrUnsupportedVersion = errors.New("unsupported version")
+
+// LibraryVersion is the version of one of the FFmpeg libraries, e.g. libavcodec
+type LibraryVersion struct {
+	Major int
+	Minor int
+	Micro int
+}
+
+func (v LibraryVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Micro)
+}
+
+// Version is the version of an ffmpeg or ffprobe build. Development builds,
+// e.g. "N-113542-g5d35b0b7a2", get their major version from libavutil and are
+// newer than every release of that major version.
+type Version struct {
+	Major         int
+	Minor         int
+	Patch         int
+	Dev           bool
+	Raw           string   // version as printed, e.g. "6.1.1-3ubuntu5"
+	Configuration []string // build configuration flags, e.g. "--enable-libx264"
+	Libraries     map[string]LibraryVersion
+}
+
+var (
+	versionLinePattern = regexp.MustCompile(`^\S+ version (\S+)`)
+	versionPattern     = regexp.MustCompile(`^n?(\d+)\.(\d+)(?:\.(\d+))?`)
+	libraryPattern     = regexp.MustCompile(`^(lib\w+)\s+(\d+)\.\s*(\d+)\.\s*(\d+)`)
+)
+
+// firstLibavutilMajor is the libavutil major version of FFmpeg 4.0
+const firstLibavutilMajor = 56
+
+// ParseVersion parses the output of ffmpeg -version or ffprobe -version
+func ParseVersion(out []byte) (Version, error) {
+	var v Version
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := versionLinePattern.FindStringSubmatch(line); match != nil && v.Raw == "" {
+			v.Raw = match[1]
+		} else if flags, ok := strings.CutPrefix(line, "configuration:"); ok {
+			v.Configuration = strings.Fields(flags)
+		} else if match := libraryPattern.FindStringSubmatch(line); match != nil {
+			if v.Libraries == nil {
+				v.Libraries = make(map[string]LibraryVersion)
+			}
+			v.Libraries[match[1]] = LibraryVersion{Major: atoi(match[2]), Minor: atoi(match[3]), Micro: atoi(match[4])}
+		}
+	}
+	if v.Raw == "" {
+		return Version{}, errors.New("invalid version output: version line not found")
+	}
+
+	if match := versionPattern.FindStringSubmatch(v.Raw); match != nil {
+		v.Major, v.Minor, v.Patch = atoi(match[1]), atoi(match[2]), atoi(match[3])
+		return v, nil
+	}
+	libavutil, ok := v.Libraries["libavutil"]
+	if !ok || libavutil.Major < firstLibavutilMajor {
+		return Version{}, fmt.Errorf("invalid version output: unknown version %q", v.Raw)
+	}
+	v.Major, v.Dev = libavutil.Major-firstLibavutilMajor+4, true
+	return v, nil
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// IsZero reports whether the version is unknown
+func (v Version) IsZero() bool {
+	return v.Raw == "" && v.Major == 0 && v.Minor == 0 && v.Patch == 0
+}
+
+// Compare returns -1, 0 or 1 when v is older than, the same as or newer than o
+func (v Version) Compare(o Version) int {
+	for _, diff := range []int{v.Major - o.Major, boolInt(v.Dev) - boolInt(o.Dev), v.Minor - o.Minor, v.Patch - o.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether v is major.minor.patch or newer
+func (v Version) AtLeast(major, minor, patch int) bool {
+	return v.Compare(Version{Major: major, Minor: minor, Patch: patch}) >= 0
+}
+
+// HasConfiguration reports whether the build was configured with flag, e.g. "--enable-libx265"
+func (v Version) HasConfiguration(flag string) bool {
+	for _, f := range v.Configuration {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (v Version) String() string {
+	if v.Dev {
+		return fmt.Sprintf("%d.x-dev (%s)", v.Major, v.Raw)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// QueryVersions runs ffmpeg -version and ffprobe -version and parses them
+func QueryVersions(ctx context.Context, cfg Configuration) (ffmpeg, ffprobe Version, err error) {
+	if ffmpeg, err = queryVersion(ctx, cfg, cfg.FFmpegBinPath()); err != nil {
+		return Version{}, Version{}, err
+	}
+	if ffprobe, err = queryVersion(ctx, cfg, cfg.FFprobeBinPath()); err != nil {
+		return Version{}, Version{}, err
+	}
+	return ffmpeg, ffprobe, nil
+}
+
+func queryVersion(ctx context.Context, cfg Configuration, bin string) (Version, error) {
+	var stdout, stderr bytes.Buffer
+	proc := cfg.Executor().Command(ctx, bin, "-version")
+	proc.Stdout, proc.Stderr = &stdout, &stderr
+	if err := proc.Run(); err != nil {
+		return Version{}, NewFFmpegError(proc.Args, err, strings.Split(strings.TrimSpace(stderr.String()), "\n"))
+	}
+	v, err := ParseVersion(stdout.Bytes())
+	if err != nil {
+		return Version{}, fmt.Errorf("%s: %w", bin, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,79 @@
+package goffmpeg
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/graux/goffmpeg/pkg/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+const versionOutput = `ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers
+built with gcc 13 (Ubuntu 13.2.0-23ubuntu3)
+configuration: --prefix=/usr --enable-gpl --enable-libx264 --enable-libx265
+libavutil      58. 29.100 / 58. 29.100
+libavcodec     60. 31.102 / 60. 31.102
+libavformat    60. 16.100 / 60. 16.100
+`
+
+func TestParseVersion(t *testing.T) {
+	t.Run("Should parse release versions, configuration and libraries", func(t *testing.T) {
+		v, err := ParseVersion([]byte(versionOutput))
+		assert.Nil(t, err)
+		assert.Equal(t, 6, v.Major)
+		assert.Equal(t, 1, v.Minor)
+		assert.Equal(t, 1, v.Patch)
+		assert.Equal(t, "6.1.1-3ubuntu5", v.Raw)
+		assert.True(t, v.HasConfiguration("--enable-libx265"))
+		assert.Equal(t, LibraryVersion{Major: 60, Minor: 31, Micro: 102}, v.Libraries["libavcodec"])
+		assert.Equal(t, "6.1.1", v.String())
+	})
+
+	t.Run("Should parse tagged and development builds", func(t *testing.T) {
+		v, err := ParseVersion([]byte("ffprobe version n7.0 Copyright (c) 2007-2024 the FFmpeg developers\n"))
+		assert.Nil(t, err)
+		assert.Equal(t, Version{Major: 7, Raw: "n7.0"}, v)
+
+		v, err = ParseVersion([]byte("ffmpeg version N-113542-g5d35b0b7a2-static https://johnvansickle.com/ffmpeg/\nlibavutil      59.  8.100 / 59.  8.100\n"))
+		assert.Nil(t, err)
+		assert.True(t, v.Dev)
+		assert.Equal(t, 7, v.Major)
+		assert.True(t, v.AtLeast(7, 1, 0))
+		assert.False(t, v.AtLeast(8, 0, 0))
+	})
+
+	t.Run("Should reject unknown output", func(t *testing.T) {
+		_, err := ParseVersion([]byte("usage: ffmpeg"))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Should compare versions", func(t *testing.T) {
+		assert.Equal(t, -1, Version{Major: 4, Minor: 4, Patch: 2}.Compare(Version{Major: 5, Minor: 1}))
+		assert.Equal(t, 0, Version{Major: 5, Minor: 1}.Compare(Version{Major: 5, Minor: 1}))
+		assert.Equal(t, 1, Version{Major: 5, Minor: 1, Patch: 4}.Compare(Version{Major: 5, Minor: 1}))
+	})
+}
+
+func TestConfigureMinimumVersion(t *testing.T) {
+	executor := func(output string) cmd.Executor {
+		return cmd.ExecutorFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "printf", "%s", output)
+		})
+	}
+
+	t.Run("Should set the versions when recent enough", func(t *testing.T) {
+		cfg, err := Configure(context.Background(), WithExecutor(executor(versionOutput)), WithFFmpegPath("ffmpeg"), WithFFprobePath("ffprobe"), WithMinimumVersion(Version{Major: 5, Minor: 1}))
+		assert.Nil(t, err)
+		assert.Equal(t, 6, cfg.FFmpegVersion().Major)
+		assert.Equal(t, 6, cfg.FFprobeVersion().Major)
+	})
+
+	t.Run("Should fail with older versions", func(t *testing.T) {
+		old := "ffmpeg version 4.4.2-0ubuntu0.22.04.1 Copyright (c) 2000-2021 the FFmpeg developers\n"
+		_, err := Configure(context.Background(), WithExecutor(executor(old)), WithFFmpegPath("ffmpeg"), WithFFprobePath("ffprobe"), WithMinimumVersion(Version{Major: 5, Minor: 1}))
+		assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+		assert.EqualError(t, err, "unsupported version: ffmpeg 4.4.2 is older than 5.1.0")
+	})
+}